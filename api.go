@@ -1,30 +1,97 @@
 package plaud
 
 import (
+	"context"
+	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
+	"slices"
 )
 
 type Server struct {
 	server     *http.ServeMux
 	listenAddr string
+	// every route registered with the server
+	routes []HTTPRoute
+	// named routes, see Server.URL
+	named map[string]RouteInfo
+
+	hub        *Hub
+	httpServer *http.Server
+	// cancelled on shutdown, every request context derives from it
+	baseCtx    context.Context
+	cancelBase context.CancelFunc
+
+	config responseConfig
+	// domain errors -> public errors, see MapError
+	errorMappings []errorMapping
 }
 
 func New(listenAddr string) *Server {
-	return &Server{
+	baseCtx, cancel := context.WithCancel(context.Background())
+	s := &Server{
 		server:     http.NewServeMux(),
 		listenAddr: listenAddr,
+		named:      make(map[string]RouteInfo),
+		hub:        NewHub(),
+		baseCtx:    baseCtx,
+		cancelBase: cancel,
 	}
+	s.httpServer = &http.Server{
+		Addr:    listenAddr,
+		Handler: s.server,
+		BaseContext: func(net.Listener) context.Context {
+			return s.baseCtx
+		},
+	}
+
+	return s
 }
 
+// panics when a route name is already taken, like the mux does for patterns
 func (s *Server) Register(routers ...HTTPRouter) {
 	for _, router := range routers {
 		router.Register()
+		routes := slices.Concat(router.GetRoutes(), router.GetHandlers())
+		for _, route := range routes {
+			s.nameRoute(route.Info())
+		}
+
+		for _, route := range routes {
+			route.setServer(s)
+			s.routes = append(s.routes, route)
+		}
 		router.RegisterServer(s.server)
 	}
 }
 
+func (s *Server) nameRoute(info RouteInfo) {
+	if info.Name == "" {
+		return
+	}
+	if existing, ok := s.named[info.Name]; ok {
+		panic(fmt.Sprintf("plaud: route name %q of %s %s is already used by %s %s",
+			info.Name, info.Method, info.Pattern, existing.Method, existing.Pattern))
+	}
+	s.named[info.Name] = info
+}
+
 func (s *Server) Run() error {
 	slog.Info("Server started on", "port", s.listenAddr)
-	return http.ListenAndServe(s.listenAddr, s.server)
+	return s.httpServer.ListenAndServe()
+}
+
+// pub/sub hub shared by every route of the server
+func (s *Server) Hub() *Hub {
+	return s.hub
+}
+
+// gracefully stops the server
+// long lived requests (event streams, websockets) see their request context cancelled
+// and the hub's subscriptions are closed
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.cancelBase()
+	s.hub.Close()
+	return s.httpServer.Shutdown(ctx)
 }
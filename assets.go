@@ -0,0 +1,126 @@
+package plaud
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// number of hex characters of the content hash put in fingerprinted names
+const fingerprintLength = 8
+
+// maps logical asset names to their fingerprinted names
+// app.js -> app.3f9a1c2b.js
+type AssetManifest struct {
+	// url prefix the assets are served under
+	prefix string
+	assets map[string]string
+	// fingerprinted name -> logical name
+	reverse map[string]string
+}
+
+// hashes every file of the fs.FS
+// prefix is the url the directory is served under, e.g. /static
+func BuildManifest(fsys fs.FS, prefix string) (*AssetManifest, error) {
+	assets := make(map[string]string)
+	err := fs.WalkDir(fsys, ".", func(name string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+
+		file, err := fsys.Open(name)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		hash := sha256.New()
+		if _, err := io.Copy(hash, file); err != nil {
+			return err
+		}
+		assets[name] = fingerprintName(name, hex.EncodeToString(hash.Sum(nil))[:fingerprintLength])
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return newAssetManifest(prefix, assets), nil
+}
+
+// reads a manifest written by WriteTo
+func LoadManifest(r io.Reader, prefix string) (*AssetManifest, error) {
+	assets := make(map[string]string)
+	if err := json.NewDecoder(r).Decode(&assets); err != nil {
+		return nil, fmt.Errorf("invalid asset manifest: %w", err)
+	}
+	return newAssetManifest(prefix, assets), nil
+}
+
+func newAssetManifest(prefix string, assets map[string]string) *AssetManifest {
+	m := &AssetManifest{
+		prefix:  strings.TrimRight(prefix, "/"),
+		assets:  assets,
+		reverse: make(map[string]string, len(assets)),
+	}
+	for name, fingerprinted := range assets {
+		m.reverse[fingerprinted] = name
+	}
+	return m
+}
+
+// app.js + 3f9a1c2b -> app.3f9a1c2b.js
+func fingerprintName(name, hash string) string {
+	ext := path.Ext(name)
+	return strings.TrimSuffix(name, ext) + "." + hash + ext
+}
+
+// writes the manifest as a json object of logical to fingerprinted names
+func (m *AssetManifest) WriteTo(w io.Writer) (int64, error) {
+	encoded, err := json.MarshalIndent(m.assets, "", "  ")
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(append(encoded, '\n'))
+	return int64(n), err
+}
+
+// url of the fingerprinted asset
+// unknown names are returned unchanged under the prefix
+func (m *AssetManifest) Asset(name string) string {
+	name = strings.TrimLeft(name, "/")
+	if fingerprinted, ok := m.assets[name]; ok {
+		name = fingerprinted
+	}
+	return m.prefix + "/" + name
+}
+
+// template functions exposing Asset as "asset"
+// usable with both html/template and text/template
+func (m *AssetManifest) FuncMap() map[string]any {
+	return map[string]any{
+		"asset": m.Asset,
+	}
+}
+
+// logical name of a fingerprinted name
+func (m *AssetManifest) resolve(name string) (string, bool) {
+	logical, ok := m.reverse[strings.TrimLeft(name, "/")]
+	if !ok {
+		return "", false
+	}
+	return "/" + logical, true
+}
+
+// serves fingerprinted names from the manifest as their original files
+// with an immutable Cache-Control
+func WithManifest(manifest *AssetManifest) FileHandlerOption {
+	return func(h *FileHandler) {
+		h.manifest = manifest
+	}
+}
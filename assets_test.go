@@ -0,0 +1,55 @@
+package plaud
+
+import (
+	"bytes"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"plaudern/utils"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestAssetManifest(t *testing.T) {
+	fsys := fstest.MapFS{
+		"app.js":          {Data: []byte("console.log(1)")},
+		"css/style.css":   {Data: []byte("body{}")},
+		"images/logo.svg": {Data: []byte("<svg/>")},
+	}
+
+	manifest, err := BuildManifest(fsys, "/static/")
+	utils.AssertNoErr(t, err)
+
+	url := manifest.Asset("app.js")
+	if !strings.HasPrefix(url, "/static/app.") || !strings.HasSuffix(url, ".js") || url == "/static/app.js" {
+		t.Fatalf("Invalid fingerprinted url %s", url)
+	}
+	utils.AssertEq(t, "/static/unknown.js", manifest.Asset("unknown.js"))
+
+	var buf bytes.Buffer
+	_, err = manifest.WriteTo(&buf)
+	utils.AssertNoErr(t, err)
+	loaded, err := LoadManifest(&buf, "/static")
+	utils.AssertNoErr(t, err)
+	utils.AssertEq(t, manifest.Asset("css/style.css"), loaded.Asset("css/style.css"))
+
+	tmpl := template.Must(template.New("page").Funcs(loaded.FuncMap()).Parse(`<script src="{{ asset "app.js" }}"></script>`))
+	var page bytes.Buffer
+	utils.AssertNoErr(t, tmpl.Execute(&page, nil))
+	utils.AssertEq(t, `<script src="`+url+`"></script>`, page.String())
+
+	server := New(":8000")
+	testRouter := NewRouter("/")
+	testRouter.ServeFS("/static", fsys, WithManifest(manifest))
+	server.Register(testRouter)
+
+	req := httptest.NewRequest(http.MethodGet, url, http.NoBody)
+	res := httptest.NewRecorder()
+	server.server.ServeHTTP(res, req)
+
+	utils.AssertEq(t, http.StatusOK, res.Code)
+	utils.AssertEq(t, "console.log(1)", res.Body.String())
+	utils.AssertEq(t, CacheImmutable, res.Header().Get("Cache-Control"))
+	utils.AssertEq(t, "text/javascript; charset=utf-8", res.Header().Get("Content-Type"))
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"math"
 	"net/http"
+	"slices"
 	"strings"
 )
 
@@ -13,7 +14,14 @@ type Context struct {
 	Middlewares    []MiddleWareFunc
 	Errors         []*Error
 
-	index int8
+	index  int8
+	server *Server
+	// response settings of the route handling the request
+	config responseConfig
+	// fields requested through SparseFields
+	fields fieldTree
+	// run once the request is handled
+	cleanups []func()
 }
 
 const abortIndex int8 = math.MaxInt8 >> 1
@@ -54,6 +62,19 @@ func (c *Context) ErrorStack() string {
 	return sb.String()
 }
 
+// registers a function to run once the request is handled
+// used to release resources like temporary files
+func (c *Context) OnDone(cleanup func()) {
+	c.cleanups = append(c.cleanups, cleanup)
+}
+
+func (c *Context) done() {
+	for i := len(c.cleanups) - 1; i >= 0; i-- {
+		c.cleanups[i]()
+	}
+	c.cleanups = nil
+}
+
 // set the middlewares from route to context
 func (c *Context) SetMiddlewares(middlewares []MiddleWareFunc) {
 	c.Middlewares = middlewares
@@ -65,7 +86,8 @@ func (c *Context) Next() {
 	c.index++
 	for c.index < int8(len(c.Middlewares)) {
 		if c.Middlewares[c.index] != nil {
-			if err := c.Middlewares[c.index](c); err != nil {
+			// errors from AbortWithError are already collected
+			if err := c.Middlewares[c.index](c); err != nil && !slices.Contains(c.Errors, err) {
 				c.Errors = append(c.Errors, err)
 			}
 		}
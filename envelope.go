@@ -0,0 +1,77 @@
+package plaud
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+)
+
+// builds the json body of a successful response from the Data
+type Envelope func(*Context, *Data) any
+
+// {"data": ..., "message": ..., "meta": ...}
+func DefaultEnvelope(_ *Context, data *Data) any {
+	return data
+}
+
+// only the data, the message and meta are left out
+func RawEnvelope(_ *Context, data *Data) any {
+	return data.Data
+}
+
+// RFC 8288 web link
+type Link struct {
+	URL string
+	// relation type, e.g. next
+	Rel string
+}
+
+// <https://example.com/users?page=2>; rel="next"
+func (l Link) String() string {
+	return fmt.Sprintf("<%s>; rel=%q", l.URL, l.Rel)
+}
+
+// wraps the responses of the server's routes
+// routers can override it with Router.SetEnvelope
+func (s *Server) SetEnvelope(envelope Envelope) *Server {
+	s.config.envelope = envelope
+	return s
+}
+
+// wraps the responses of the router's routes
+// applies to the routers nested in it that don't set their own
+func (r *Router) SetEnvelope(envelope Envelope) HTTPRouter {
+	r.config.envelope = envelope
+	return r
+}
+
+// writes the headers and cookies of the Data, then the enveloped body
+func (c *Context) renderData(data *Data) *Error {
+	if err := c.selectFields(data); err != nil {
+		return err
+	}
+
+	links := data.links
+	if data.page != nil {
+		data.SetMeta("pagination", data.page.meta())
+		links = slices.Concat(links, data.page.links(c.Request.URL))
+	}
+
+	header := c.ResponseWriter.Header()
+	for key, values := range data.header {
+		header[key] = values
+	}
+	for _, cookie := range data.cookies {
+		http.SetCookie(c.ResponseWriter, cookie)
+	}
+	for _, link := range links {
+		header.Add("Link", link.String())
+	}
+
+	envelope := c.responseConfig().envelope
+	if envelope == nil {
+		envelope = DefaultEnvelope
+	}
+	c.JSON(data.code, envelope(c, data))
+	return nil
+}
@@ -0,0 +1,83 @@
+package plaud
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"plaudern/utils"
+	"testing"
+)
+
+func TestEnvelope(t *testing.T) {
+	users := func(ctx *Context) (*Data, *Error) {
+		return NewData("Users").
+			SetData([]string{"Jotaro", "Joseph"}).
+			SetMeta("total", 2).
+			SetHeader("X-Total-Count", "2").
+			SetCookie(&http.Cookie{Name: "session", Value: "abc"}).
+			SetLinks(Link{URL: "/users?page=2", Rel: "next"}), nil
+	}
+
+	server := New(":8000")
+	testRouter := NewRouter("/")
+	testRouter.Get("/users", users)
+
+	rawRouter := NewRouter("/raw")
+	rawRouter.SetEnvelope(RawEnvelope)
+	rawRouter.Get("/users", users)
+
+	customRouter := NewRouter("/custom")
+	customRouter.SetEnvelope(func(ctx *Context, data *Data) any {
+		return map[string]any{"result": data.Data, "ok": true}
+	})
+	customRouter.Get("/users", users)
+	server.Register(testRouter, rawRouter, customRouter)
+
+	tests := []struct {
+		path string
+		body string
+	}{
+		{"/users", "{\"data\":[\"Jotaro\",\"Joseph\"],\"message\":\"Users\",\"meta\":{\"total\":2}}\n"},
+		{"/raw/users", "[\"Jotaro\",\"Joseph\"]\n"},
+		{"/custom/users", "{\"ok\":true,\"result\":[\"Jotaro\",\"Joseph\"]}\n"},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodGet, test.path, nil)
+		res := httptest.NewRecorder()
+		server.server.ServeHTTP(res, req)
+
+		utils.AssertEq(t, http.StatusOK, res.Code)
+		utils.AssertEq(t, test.body, res.Body.String())
+		utils.AssertEq(t, "2", res.Header().Get("X-Total-Count"))
+		utils.AssertEq(t, "session=abc", res.Header().Get("Set-Cookie"))
+		utils.AssertEq(t, "</users?page=2>; rel=\"next\"", res.Header().Get("Link"))
+	}
+}
+
+func TestEnvelopeInheritance(t *testing.T) {
+	server := New(":8000").SetEnvelope(RawEnvelope)
+	testRouter := NewRouter("/")
+	childRouter := NewRouter("/")
+	childRouter.SetEnvelope(DefaultEnvelope)
+	childRouter.Get("/", func(ctx *Context) (*Data, *Error) {
+		return NewData("Child").SetData(1), nil
+	})
+	testRouter.Get("/parent", func(ctx *Context) (*Data, *Error) {
+		return NewData("Parent").SetData(1), nil
+	})
+	testRouter.Handle("/child", childRouter)
+	server.Register(testRouter)
+
+	req := httptest.NewRequest(http.MethodGet, "/parent", nil)
+	res := httptest.NewRecorder()
+	server.server.ServeHTTP(res, req)
+	utils.AssertEq(t, "1\n", res.Body.String())
+
+	req = httptest.NewRequest(http.MethodGet, "/child", nil)
+	res = httptest.NewRecorder()
+	server.server.ServeHTTP(res, req)
+
+	var body Data
+	utils.AssertNoErr(t, json.NewDecoder(res.Body).Decode(&body))
+	utils.AssertEq(t, "Child", body.Message)
+}
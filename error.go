@@ -5,7 +5,21 @@ import "net/http"
 type Error struct {
 	Data    interface{} `json:"data"`
 	Message string      `json:"message"`
-	code    int
+	// stable machine readable code, e.g. user_not_found
+	ErrorCode string `json:"code,omitempty"`
+	code      int
+
+	// underlying error, logged but never sent to the client
+	cause error
+	// resolved through the server's error mappings, see WrapError
+	mappable bool
+
+	// problem details members, only rendered with the ProblemDetails format
+	problemType string
+	title       string
+	detail      string
+	instance    string
+	extensions  map[string]any
 }
 
 func (e *Error) Error() string {
@@ -29,3 +43,62 @@ func (e *Error) SetData(data interface{}) *Error {
 	e.Data = data
 	return e
 }
+
+func (e *Error) SetErrorCode(code string) *Error {
+	e.ErrorCode = code
+	return e
+}
+
+// wraps the underlying error, errors.Is and errors.As see through the Error
+func (e *Error) Wrap(err error) *Error {
+	e.cause = err
+	return e
+}
+
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+// wraps a domain error so handlers can return it as is
+// the status and message come from the mappings registered with Server.MapError,
+// unmapped errors answer 500 Internal Server Error
+func WrapError(err error) *Error {
+	wrapped := NewError(http.StatusText(http.StatusInternalServerError)).
+		SetCode(http.StatusInternalServerError).
+		Wrap(err)
+	wrapped.mappable = true
+	return wrapped
+}
+
+// uri identifying the problem type, defaults to about:blank
+func (e *Error) SetType(problemType string) *Error {
+	e.problemType = problemType
+	return e
+}
+
+// short summary of the problem type, defaults to the status text
+func (e *Error) SetTitle(title string) *Error {
+	e.title = title
+	return e
+}
+
+// explanation specific to this occurrence, defaults to the message
+func (e *Error) SetDetail(detail string) *Error {
+	e.detail = detail
+	return e
+}
+
+// uri identifying this occurrence of the problem
+func (e *Error) SetInstance(instance string) *Error {
+	e.instance = instance
+	return e
+}
+
+// adds an extension member to the problem details
+func (e *Error) SetExtension(key string, value any) *Error {
+	if e.extensions == nil {
+		e.extensions = make(map[string]any)
+	}
+	e.extensions[key] = value
+	return e
+}
@@ -0,0 +1,99 @@
+package plaud
+
+import (
+	"log/slog"
+	"net/http"
+)
+
+// decides how the errors collected during a request are rendered and logged
+// runs at most once per request, and only if the response wasn't written yet
+// the errors are in the order they were collected and already resolved
+// through the server's error mappings
+type ErrorHandler func(*Context, []*Error)
+
+// renders the last error collected
+func DefaultErrorHandler(ctx *Context, errs []*Error) {
+	ctx.RenderError(errs[len(errs)-1])
+}
+
+// handles the errors of the server's routes
+// routers can override it with Router.SetErrorHandler
+func (s *Server) SetErrorHandler(handler ErrorHandler) *Server {
+	s.config.handler = handler
+	return s
+}
+
+// handles the errors of the router's routes
+// applies to the routers nested in it that don't set their own
+func (r *Router) SetErrorHandler(handler ErrorHandler) HTTPRouter {
+	r.config.handler = handler
+	return r
+}
+
+// status code of the error
+func (e *Error) Code() int {
+	return e.code
+}
+
+// resolves the collected errors and passes them to the error handler
+// errors collected after the response was written are only logged
+func (c *Context) handleErrors() {
+	if len(c.Errors) == 0 {
+		return
+	}
+
+	errs := make([]*Error, len(c.Errors))
+	for i, err := range c.Errors {
+		errs[i] = c.resolveError(err)
+	}
+
+	if c.Written() {
+		for _, err := range errs {
+			slog.Warn("Error after the response was written",
+				"method", c.Request.Method,
+				"path", c.Request.URL.Path,
+				"status", err.code,
+				"error", err.Message,
+			)
+		}
+		return
+	}
+
+	handler := c.responseConfig().handler
+	if handler == nil {
+		handler = DefaultErrorHandler
+	}
+	handler(c, errs)
+}
+
+// reports whether the status or a part of the body was sent
+func (c *Context) Written() bool {
+	if w, ok := c.ResponseWriter.(*responseWriter); ok {
+		return w.written
+	}
+	return false
+}
+
+// records whether the response was written
+// Unwrap lets http.ResponseController reach the Flusher and Hijacker underneath
+type responseWriter struct {
+	http.ResponseWriter
+	written bool
+}
+
+func (w *responseWriter) WriteHeader(code int) {
+	// informational responses are followed by the final one
+	if code >= http.StatusOK {
+		w.written = true
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *responseWriter) Write(data []byte) (int, error) {
+	w.written = true
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,105 @@
+package plaud
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"plaudern/utils"
+	"testing"
+)
+
+func TestDefaultErrorHandler(t *testing.T) {
+	server := New(":8000")
+	testRouter := NewRouter("/")
+	testRouter.Get("/fail", func(ctx *Context) (*Data, *Error) {
+		return nil, NewError("Failed").SetCode(http.StatusConflict)
+	})
+	server.Register(testRouter)
+
+	req := httptest.NewRequest(http.MethodGet, "/fail", nil)
+	res := httptest.NewRecorder()
+	server.server.ServeHTTP(res, req)
+
+	// rendered once
+	utils.AssertEq(t, http.StatusConflict, res.Code)
+	utils.AssertEq(t, "{\"data\":null,\"message\":\"Failed\"}\n", res.Body.String())
+}
+
+func TestErrorHandler(t *testing.T) {
+	calls := 0
+	aggregate := func(ctx *Context, errs []*Error) {
+		calls++
+		messages := make([]string, len(errs))
+		for i, err := range errs {
+			messages[i] = err.Message
+		}
+		ctx.JSON(errs[0].Code(), messages)
+	}
+
+	validate := func(ctx *Context) *Error {
+		ctx.Errors = append(ctx.Errors, NewError("name is required"))
+		ctx.Next()
+		return nil
+	}
+	failing := func(ctx *Context) (*Data, *Error) {
+		return nil, NewError("age is required")
+	}
+
+	server := New(":8000").SetErrorHandler(aggregate)
+	testRouter := NewRouter("/")
+	testRouter.Get("/aggregate", failing).Use(validate)
+	testRouter.Get("/written", func(ctx *Context) (*Data, *Error) {
+		ctx.JSON(http.StatusOK, "partial")
+		return nil, NewError("Too late")
+	})
+	testRouter.Get("/abort", failing).Use(func(ctx *Context) *Error {
+		return ctx.AbortWithError("Unauthorized", http.StatusUnauthorized)
+	})
+
+	childRouter := NewRouter("/")
+	childRouter.SetErrorHandler(DefaultErrorHandler)
+	childRouter.Get("/", failing).Use(validate)
+	testRouter.Handle("/child", childRouter)
+	server.Register(testRouter)
+
+	tests := []struct {
+		path  string
+		code  int
+		body  []string
+		calls int
+	}{
+		{"/aggregate", http.StatusBadRequest, []string{"name is required", "age is required"}, 1},
+		// the response was already written, the handler isn't called
+		{"/written", http.StatusOK, nil, 0},
+		// errors from AbortWithError are collected once
+		{"/abort", http.StatusUnauthorized, []string{"Unauthorized"}, 1},
+	}
+	for _, test := range tests {
+		calls = 0
+		req := httptest.NewRequest(http.MethodGet, test.path, nil)
+		res := httptest.NewRecorder()
+		server.server.ServeHTTP(res, req)
+		utils.AssertEq(t, test.code, res.Code)
+		utils.AssertEq(t, test.calls, calls)
+
+		if test.body != nil {
+			var body []string
+			utils.AssertNoErr(t, json.NewDecoder(res.Body).Decode(&body))
+			utils.AssertEq(t, len(test.body), len(body))
+			for i := range body {
+				utils.AssertEq(t, test.body[i], body[i])
+			}
+		}
+	}
+
+	// nested routers keep their own handler
+	calls = 0
+	req := httptest.NewRequest(http.MethodGet, "/child", nil)
+	res := httptest.NewRecorder()
+	server.server.ServeHTTP(res, req)
+	utils.AssertEq(t, 0, calls)
+
+	var body Error
+	utils.AssertNoErr(t, json.NewDecoder(res.Body).Decode(&body))
+	utils.AssertEq(t, "age is required", body.Message)
+}
@@ -0,0 +1,64 @@
+package plaud
+
+import (
+	"errors"
+	"log/slog"
+)
+
+type errorMapping struct {
+	matches func(error) bool
+	public  *Error
+}
+
+// answers errors matching target (errors.Is) with the public error
+// applies to errors created with WrapError, the first matching mapping wins
+//
+//	server.MapError(sql.ErrNoRows, NewError("Not found").SetCode(http.StatusNotFound))
+func (s *Server) MapError(target error, public *Error) *Server {
+	s.errorMappings = append(s.errorMappings, errorMapping{
+		matches: func(err error) bool { return errors.Is(err, target) },
+		public:  public,
+	})
+	return s
+}
+
+// answers errors of type T (errors.As) with the public error
+//
+//	MapErrorType[*ValidationError](server, NewError("Invalid input").SetCode(http.StatusUnprocessableEntity))
+func MapErrorType[T error](s *Server, public *Error) *Server {
+	s.errorMappings = append(s.errorMappings, errorMapping{
+		matches: func(err error) bool {
+			var target T
+			return errors.As(err, &target)
+		},
+		public: public,
+	})
+	return s
+}
+
+// maps a wrapped domain error to its public error and logs the cause
+// the returned error is a copy, the registered errors are never modified
+func (c *Context) resolveError(err *Error) *Error {
+	if err.cause == nil {
+		return err
+	}
+
+	if err.mappable && c.server != nil {
+		for _, mapping := range c.server.errorMappings {
+			if mapping.matches(err.cause) {
+				resolved := *mapping.public
+				resolved.cause = err.cause
+				err = &resolved
+				break
+			}
+		}
+	}
+
+	slog.Error("Request failed",
+		"method", c.Request.Method,
+		"path", c.Request.URL.Path,
+		"status", err.code,
+		"error", err.cause,
+	)
+	return err
+}
@@ -0,0 +1,90 @@
+package plaud
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"plaudern/utils"
+	"strings"
+	"testing"
+)
+
+type quotaError struct {
+	limit int
+}
+
+func (e *quotaError) Error() string {
+	return fmt.Sprintf("quota of %d exceeded", e.limit)
+}
+
+func TestErrorWrap(t *testing.T) {
+	err := NewError("User not found").SetCode(http.StatusNotFound).Wrap(sql.ErrNoRows)
+	utils.AssertEq(t, true, errors.Is(err, sql.ErrNoRows))
+	utils.AssertEq(t, "User not found", err.Error())
+
+	var target *quotaError
+	wrapped := WrapError(fmt.Errorf("upload: %w", &quotaError{limit: 10}))
+	utils.AssertEq(t, true, errors.As(wrapped, &target))
+	utils.AssertEq(t, 10, target.limit)
+}
+
+func TestErrorMapping(t *testing.T) {
+	notFound := NewError("Not found").SetCode(http.StatusNotFound).SetErrorCode("not_found")
+
+	server := New(":8000").MapError(sql.ErrNoRows, notFound)
+	server.MapError(context.DeadlineExceeded, NewError("Timed out").SetCode(http.StatusGatewayTimeout))
+	MapErrorType[*quotaError](server, NewError("Quota exceeded").SetCode(http.StatusTooManyRequests))
+
+	causes := map[string]error{
+		"missing": fmt.Errorf("select user 42: %w", sql.ErrNoRows),
+		"slow":    context.DeadlineExceeded,
+		"quota":   &quotaError{limit: 10},
+		"secret":  errors.New("dial tcp 10.0.0.3:5432: connection refused"),
+	}
+	testRouter := NewRouter("/")
+	testRouter.Get("/{cause}", func(ctx *Context) (*Data, *Error) {
+		return nil, WrapError(causes[ctx.Request.PathValue("cause")])
+	})
+	testRouter.Get("/explicit", func(ctx *Context) (*Data, *Error) {
+		return nil, NewError("Gone").SetCode(http.StatusGone).Wrap(sql.ErrNoRows)
+	})
+	server.Register(testRouter)
+
+	tests := []struct {
+		path    string
+		code    int
+		message string
+	}{
+		{"/missing", http.StatusNotFound, "Not found"},
+		{"/slow", http.StatusGatewayTimeout, "Timed out"},
+		{"/quota", http.StatusTooManyRequests, "Quota exceeded"},
+		{"/secret", http.StatusInternalServerError, "Internal Server Error"},
+		// errors built by the handler keep their status
+		{"/explicit", http.StatusGone, "Gone"},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodGet, test.path, nil)
+		res := httptest.NewRecorder()
+		server.server.ServeHTTP(res, req)
+		utils.AssertEq(t, test.code, res.Code)
+		utils.AssertEq(t, false, strings.Contains(res.Body.String(), "10.0.0.3"))
+
+		var body Error
+		utils.AssertNoErr(t, json.NewDecoder(res.Body).Decode(&body))
+		utils.AssertEq(t, test.message, body.Message)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/missing", nil)
+	res := httptest.NewRecorder()
+	server.server.ServeHTTP(res, req)
+
+	var body Error
+	utils.AssertNoErr(t, json.NewDecoder(res.Body).Decode(&body))
+	utils.AssertEq(t, "not_found", body.ErrorCode)
+	// the registered error is left untouched
+	utils.AssertEq(t, nil, notFound.Unwrap())
+}
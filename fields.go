@@ -0,0 +1,156 @@
+package plaud
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// query parameter read by SparseFields
+const FieldsParam = "fields"
+
+// requested fields, nested by path
+// a nil subtree keeps the whole value
+type fieldTree map[string]fieldTree
+
+// middleware pruning Data.Data to the fields listed in ?fields=id,name,owner.email
+// arrays are pruned element by element
+// fields unknown to the type of Data.Data answer 400
+func SparseFields(ctx *Context) *Error {
+	raw := ctx.Request.URL.Query().Get(FieldsParam)
+	if raw == "" {
+		return nil
+	}
+
+	tree, err := parseFields(raw)
+	if err != nil {
+		ctx.Abort()
+		return err
+	}
+	ctx.fields = tree
+	return nil
+}
+
+func parseFields(raw string) (fieldTree, *Error) {
+	tree := make(fieldTree)
+	for _, path := range strings.Split(raw, ",") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+
+		node := tree
+		segments := strings.Split(path, ".")
+		for i, segment := range segments {
+			if segment == "" {
+				return nil, NewError(fmt.Sprintf("Invalid field %s", path)).SetCode(http.StatusBadRequest)
+			}
+
+			child, ok := node[segment]
+			if ok && child == nil {
+				// the whole value is already requested
+				break
+			}
+			if i == len(segments)-1 {
+				node[segment] = nil
+				break
+			}
+			if !ok {
+				child = make(fieldTree)
+				node[segment] = child
+			}
+			node = child
+		}
+	}
+	return tree, nil
+}
+
+var jsonMarshalerType = reflect.TypeFor[json.Marshaler]()
+
+// checks the requested fields against the json fields of t
+// maps, interfaces and custom marshalers can't be checked and accept any field
+func (tree fieldTree) validate(t reflect.Type, prefix string) *Error {
+	for t != nil && (t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice || t.Kind() == reflect.Array) {
+		if t.Kind() != reflect.Pointer && t.Elem().Kind() == reflect.Uint8 {
+			break
+		}
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() == reflect.Map || t.Kind() == reflect.Interface ||
+		t.Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(jsonMarshalerType) {
+		return nil
+	}
+
+	fields := make(map[string]reflect.Type)
+	if t.Kind() == reflect.Struct {
+		for _, field := range jsonFields(t) {
+			fields[field.name] = field.Type
+		}
+	}
+
+	for name, subtree := range tree {
+		fieldType, ok := fields[name]
+		if !ok {
+			return NewError(fmt.Sprintf("Unknown field %s%s", prefix, name)).SetCode(http.StatusBadRequest)
+		}
+		if subtree != nil {
+			if err := subtree.validate(fieldType, prefix+name+"."); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (tree fieldTree) prune(value any) any {
+	switch value := value.(type) {
+	case map[string]any:
+		pruned := make(map[string]any, len(tree))
+		for name, subtree := range tree {
+			child, ok := value[name]
+			if !ok {
+				continue
+			}
+			if subtree != nil {
+				child = subtree.prune(child)
+			}
+			pruned[name] = child
+		}
+		return pruned
+	case []any:
+		for i, element := range value {
+			value[i] = tree.prune(element)
+		}
+		return value
+	default:
+		return value
+	}
+}
+
+// replaces Data.Data with its requested fields
+func (c *Context) selectFields(data *Data) *Error {
+	if c.fields == nil || data.Data == nil {
+		return nil
+	}
+	if err := c.fields.validate(reflect.TypeOf(data.Data), ""); err != nil {
+		return err
+	}
+
+	encoded, err := json.Marshal(data.Data)
+	if err != nil {
+		return NewError("Failed to encode JSON").SetCode(http.StatusInternalServerError)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	// keeps large integers intact
+	decoder.UseNumber()
+
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return NewError("Failed to encode JSON").SetCode(http.StatusInternalServerError)
+	}
+	data.Data = c.fields.prune(value)
+	return nil
+}
@@ -0,0 +1,124 @@
+package plaud
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"plaudern/utils"
+	"testing"
+)
+
+type fieldOwner struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+type fieldRepo struct {
+	ID     int64        `json:"id"`
+	Name   string       `json:"name"`
+	Secret string       `json:"-"`
+	Owner  *fieldOwner  `json:"owner"`
+	Forks  []fieldOwner `json:"forks"`
+	Labels map[string]string
+}
+
+func TestSparseFields(t *testing.T) {
+	repos := []fieldRepo{{
+		ID:     9007199254740993,
+		Name:   "plaudern",
+		Owner:  &fieldOwner{Name: "Jotaro", Email: "jotaro@example.com"},
+		Forks:  []fieldOwner{{Name: "Joseph", Email: "joseph@example.com"}},
+		Labels: map[string]string{"lang": "go"},
+	}}
+
+	server := New(":8000")
+	testRouter := NewRouter("/")
+	testRouter.Use(SparseFields)
+	testRouter.Get("/repos", func(ctx *Context) (*Data, *Error) {
+		return NewData("Repos").SetData(repos), nil
+	})
+	testRouter.Get("/raw", func(ctx *Context) (*Data, *Error) {
+		return NewData("Raw").SetData(map[string]any{"id": 1, "name": "plaudern"}), nil
+	})
+	server.Register(testRouter)
+
+	tests := []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/repos?fields=id,owner.email", http.StatusOK,
+			"{\"data\":[{\"id\":9007199254740993,\"owner\":{\"email\":\"jotaro@example.com\"}}],\"message\":\"Repos\"}\n"},
+		{"/repos?fields=forks.name,owner.name,owner", http.StatusOK,
+			"{\"data\":[{\"forks\":[{\"name\":\"Joseph\"}],\"owner\":{\"email\":\"jotaro@example.com\",\"name\":\"Jotaro\"}}],\"message\":\"Repos\"}\n"},
+		{"/repos?fields=Labels.lang", http.StatusOK,
+			"{\"data\":[{\"Labels\":{\"lang\":\"go\"}}],\"message\":\"Repos\"}\n"},
+		{"/repos", http.StatusOK, ""},
+		{"/repos?fields=owner.phone", http.StatusBadRequest,
+			"{\"data\":null,\"message\":\"Unknown field owner.phone\"}\n"},
+		{"/repos?fields=Secret", http.StatusBadRequest, ""},
+		{"/repos?fields=name.first", http.StatusBadRequest, ""},
+		{"/repos?fields=owner..name", http.StatusBadRequest, ""},
+		// untyped data can't be checked
+		{"/raw?fields=name,unknown", http.StatusOK, "{\"data\":{\"name\":\"plaudern\"},\"message\":\"Raw\"}\n"},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodGet, test.path, nil)
+		res := httptest.NewRecorder()
+		server.server.ServeHTTP(res, req)
+
+		utils.AssertEq(t, test.code, res.Code)
+		if test.body != "" {
+			utils.AssertEq(t, test.body, res.Body.String())
+		}
+	}
+}
+
+type fieldMeta struct {
+	Version int `json:"version"`
+}
+
+type fieldTimestamps struct {
+	Created string `json:"created"`
+}
+
+type fieldDocument struct {
+	fieldMeta `json:"meta"`
+	fieldTimestamps
+	Title string `json:"title"`
+}
+
+func TestSparseFieldsEmbedded(t *testing.T) {
+	server := New(":8000")
+	testRouter := NewRouter("/")
+	testRouter.Use(SparseFields)
+	testRouter.Get("/document", func(ctx *Context) (*Data, *Error) {
+		return NewData("Document").SetData(fieldDocument{
+			fieldMeta:       fieldMeta{Version: 2},
+			fieldTimestamps: fieldTimestamps{Created: "today"},
+			Title:           "Stardust",
+		}), nil
+	})
+	server.Register(testRouter)
+
+	tests := []struct {
+		path string
+		code int
+		body string
+	}{
+		// embedded structs with a json name are nested under it
+		{"/document?fields=meta.version", http.StatusOK, "{\"data\":{\"meta\":{\"version\":2}},\"message\":\"Document\"}\n"},
+		// the others are flattened
+		{"/document?fields=created", http.StatusOK, "{\"data\":{\"created\":\"today\"},\"message\":\"Document\"}\n"},
+		{"/document?fields=version", http.StatusBadRequest, ""},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodGet, test.path, nil)
+		res := httptest.NewRecorder()
+		server.server.ServeHTTP(res, req)
+
+		utils.AssertEq(t, test.code, res.Code)
+		if test.body != "" {
+			utils.AssertEq(t, test.body, res.Body.String())
+		}
+	}
+}
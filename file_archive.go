@@ -0,0 +1,164 @@
+package plaud
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"path"
+	"strings"
+)
+
+type archiveEntry struct {
+	name string
+	info fs.FileInfo
+}
+
+// requesting a directory with ?download=zip or ?download=tar.gz streams an archive of it
+// hidden and denied files are skipped, directories larger than maxSize bytes answer 413
+// a maxSize of 0 means unlimited
+func WithArchiveDownloads(maxSize int64) FileHandlerOption {
+	return func(h *FileHandler) {
+		h.archives = true
+		h.archiveMaxSize = maxSize
+	}
+}
+
+func (h *FileHandler) serveArchive(ctx *Context, dir, format string) *Error {
+	if format != "zip" && format != "tar.gz" {
+		return NewError("Unsupported archive format").SetCode(http.StatusBadRequest)
+	}
+
+	var (
+		entries []archiveEntry
+		total   int64
+	)
+	err := h.walk(dir, func(name string, info fs.FileInfo) error {
+		entries = append(entries, archiveEntry{name: name, info: info})
+		total += info.Size()
+		return nil
+	})
+	if err != nil {
+		return NewError("Failed to read directory").SetCode(http.StatusInternalServerError)
+	}
+	if h.archiveMaxSize > 0 && total > h.archiveMaxSize {
+		return NewError("Directory too large to archive").SetCode(http.StatusRequestEntityTooLarge)
+	}
+
+	base := path.Base(dir)
+	if base == "/" {
+		base = "archive"
+	}
+	ctx.setDisposition("attachment", base+"."+format)
+
+	if format == "zip" {
+		ctx.Header("Content-Type", "application/zip")
+		ctx.Status(http.StatusOK)
+		err = h.writeZip(ctx.ResponseWriter, dir, entries)
+	} else {
+		ctx.Header("Content-Type", "application/gzip")
+		ctx.Status(http.StatusOK)
+		err = h.writeTarGz(ctx.ResponseWriter, dir, entries)
+	}
+	// the status is already sent, the connection is dropped
+	// so the client doesn't take the cut archive as complete
+	if err != nil {
+		slog.Error("Archive download failed", "path", ctx.Request.URL.Path, "error", err)
+		panic(http.ErrAbortHandler)
+	}
+	return nil
+}
+
+// calls fn for every regular file below dir which isn't denied
+func (h *FileHandler) walk(dir string, fn func(string, fs.FileInfo) error) error {
+	file, err := h.dir.Open(dir)
+	if err != nil {
+		return err
+	}
+	infos, err := file.Readdir(-1)
+	file.Close()
+	if err != nil {
+		return err
+	}
+
+	for _, info := range infos {
+		name := path.Join(dir, info.Name())
+		if h.isDenied(name) {
+			continue
+		}
+		switch {
+		case info.IsDir():
+			if err := h.walk(name, fn); err != nil {
+				return err
+			}
+		case info.Mode().IsRegular():
+			if err := fn(name, info); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (h *FileHandler) writeZip(w io.Writer, dir string, entries []archiveEntry) error {
+	archive := zip.NewWriter(w)
+	for _, entry := range entries {
+		header, err := zip.FileInfoHeader(entry.info)
+		if err != nil {
+			return err
+		}
+		header.Name = strings.TrimPrefix(strings.TrimPrefix(entry.name, dir), "/")
+		header.Method = zip.Deflate
+
+		writer, err := archive.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		if err := h.copyFile(writer, entry.name, -1); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
+
+func (h *FileHandler) writeTarGz(w io.Writer, dir string, entries []archiveEntry) error {
+	compressed := gzip.NewWriter(w)
+	archive := tar.NewWriter(compressed)
+	for _, entry := range entries {
+		header, err := tar.FileInfoHeader(entry.info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = strings.TrimPrefix(strings.TrimPrefix(entry.name, dir), "/")
+
+		if err := archive.WriteHeader(header); err != nil {
+			return err
+		}
+		// tar needs exactly the size from the header
+		if err := h.copyFile(archive, entry.name, entry.info.Size()); err != nil {
+			return err
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return err
+	}
+	return compressed.Close()
+}
+
+func (h *FileHandler) copyFile(w io.Writer, name string, size int64) error {
+	file, err := h.dir.Open(name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if size < 0 {
+		_, err = io.Copy(w, file)
+		return err
+	}
+	_, err = io.CopyN(w, file, size)
+	return err
+}
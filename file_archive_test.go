@@ -0,0 +1,121 @@
+package plaud
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"plaudern/utils"
+	"slices"
+	"testing"
+	"testing/fstest"
+)
+
+func TestStaticFilesArchive(t *testing.T) {
+	fsys := fstest.MapFS{
+		"reports/summary.txt":   {Data: []byte("summary")},
+		"reports/daily/mon.txt": {Data: []byte("monday")},
+		"reports/.secret":       {Data: []byte("hidden")},
+		"big/blob.bin":          {Data: bytes.Repeat([]byte("x"), 64)},
+	}
+
+	server := New(":8000")
+	testRouter := NewRouter("/")
+	testRouter.ServeFS("/qa", fsys, WithArchiveDownloads(32), WithDenyHidden())
+	server.Register(testRouter)
+
+	req := httptest.NewRequest(http.MethodGet, "/qa/reports/?download=zip", http.NoBody)
+	res := httptest.NewRecorder()
+	server.server.ServeHTTP(res, req)
+
+	utils.AssertEq(t, http.StatusOK, res.Code)
+	utils.AssertEq(t, "attachment; filename=reports.zip", res.Header().Get("Content-Disposition"))
+	zipReader, err := zip.NewReader(bytes.NewReader(res.Body.Bytes()), int64(res.Body.Len()))
+	utils.AssertNoErr(t, err)
+	var names []string
+	for _, file := range zipReader.File {
+		names = append(names, file.Name)
+	}
+	slices.Sort(names)
+	if !slices.Equal(names, []string{"daily/mon.txt", "summary.txt"}) {
+		t.Fatalf("Unexpected zip contents %v", names)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/qa/reports/?download=tar.gz", http.NoBody)
+	res = httptest.NewRecorder()
+	server.server.ServeHTTP(res, req)
+
+	utils.AssertEq(t, http.StatusOK, res.Code)
+	gzipReader, err := gzip.NewReader(res.Body)
+	utils.AssertNoErr(t, err)
+	tarReader := tar.NewReader(gzipReader)
+	contents := make(map[string]string)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		utils.AssertNoErr(t, err)
+		data, err := io.ReadAll(tarReader)
+		utils.AssertNoErr(t, err)
+		contents[header.Name] = string(data)
+	}
+	utils.AssertEq(t, 2, len(contents))
+	utils.AssertEq(t, "monday", contents["daily/mon.txt"])
+
+	req = httptest.NewRequest(http.MethodGet, "/qa/big/?download=zip", http.NoBody)
+	res = httptest.NewRecorder()
+	server.server.ServeHTTP(res, req)
+	utils.AssertEq(t, http.StatusRequestEntityTooLarge, res.Code)
+}
+
+// files named broken.txt fail to read
+type brokenFS struct {
+	fstest.MapFS
+}
+
+func (b brokenFS) Open(name string) (fs.File, error) {
+	file, err := b.MapFS.Open(name)
+	if err != nil || path.Base(name) != "broken.txt" {
+		return file, err
+	}
+	return brokenFile{file}, nil
+}
+
+type brokenFile struct {
+	fs.File
+}
+
+func (brokenFile) Read([]byte) (int, error) {
+	return 0, errors.New("disk failure")
+}
+
+func TestStaticFilesArchiveAbort(t *testing.T) {
+	fsys := brokenFS{fstest.MapFS{
+		"reports/a.txt":      {Data: []byte("a")},
+		"reports/broken.txt": {Data: []byte("broken")},
+	}}
+
+	server := New(":8000")
+	testRouter := NewRouter("/")
+	testRouter.ServeFS("/qa", fsys, WithArchiveDownloads(0))
+	server.Register(testRouter)
+
+	httpServer := httptest.NewServer(server.server)
+	defer httpServer.Close()
+
+	// the connection is dropped instead of ending the archive cleanly,
+	// before or after the headers were flushed
+	res, err := http.Get(httpServer.URL + "/qa/reports/?download=zip")
+	if err == nil {
+		defer res.Body.Close()
+		_, err = io.ReadAll(res.Body)
+	}
+	utils.AssertNoEq(t, nil, err)
+}
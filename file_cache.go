@@ -0,0 +1,186 @@
+package plaud
+
+import (
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Cache-Control value for files that never change under the same name
+const CacheImmutable = "public, max-age=31536000, immutable"
+
+// matches fingerprinted names like app.3f9a1c2b.js
+var fingerprintPattern = regexp.MustCompile(`\.[0-9a-fA-F]{6,}\.[^./]+$`)
+
+// sets the Cache-Control header for the files it matches
+// the first matching rule wins
+type CacheRule struct {
+	// glob matched against the base name of the file, see path.Match
+	Pattern string
+	// used instead of Pattern when set
+	Match func(name string) bool
+	Value string
+}
+
+// long lived immutable caching for fingerprinted file names
+var FingerprintedCacheRule = CacheRule{
+	Match: func(name string) bool { return fingerprintPattern.MatchString(name) },
+	Value: CacheImmutable,
+}
+
+// html is always revalidated
+var HTMLCacheRule = CacheRule{
+	Pattern: "*.html",
+	Value:   "no-cache",
+}
+
+func (rule CacheRule) matches(name string) bool {
+	if rule.Match != nil {
+		return rule.Match(name)
+	}
+	matched, err := path.Match(rule.Pattern, path.Base(name))
+	return err == nil && matched
+}
+
+// sets Cache-Control headers based on the rules
+func WithCacheControl(rules ...CacheRule) FileHandlerOption {
+	return func(h *FileHandler) {
+		h.cacheRules = append(h.cacheRules, rules...)
+	}
+}
+
+// serves file.br or file.gz instead of file when the client accepts the encoding
+func WithPrecompressed() FileHandlerOption {
+	return func(h *FileHandler) {
+		h.precompressed = true
+	}
+}
+
+// computes content hash ETags for every file of the fs.FS once
+// enabled by default for embed.FS, which has no modification times
+func WithETags(fsys fs.FS) FileHandlerOption {
+	return func(h *FileHandler) {
+		h.etags = computeETags(fsys)
+	}
+}
+
+// serves the contents of a fs.FS
+func NewFileHandlerFS(fsys fs.FS, path string, opts ...FileHandlerOption) *FileHandler {
+	switch fsys.(type) {
+	case embed.FS, *embed.FS:
+		opts = append([]FileHandlerOption{WithETags(fsys)}, opts...)
+	}
+
+	return NewFileHandler(http.FS(fsys), path, opts...)
+}
+
+func computeETags(fsys fs.FS) map[string]string {
+	etags := make(map[string]string)
+	err := fs.WalkDir(fsys, ".", func(name string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+
+		file, err := fsys.Open(name)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		hash := sha256.New()
+		if _, err := io.Copy(hash, file); err != nil {
+			return err
+		}
+		etags["/"+name] = `"` + hex.EncodeToString(hash.Sum(nil)[:16]) + `"`
+		return nil
+	})
+	if err != nil {
+		slog.Error("Failed to compute ETags", "error", err)
+	}
+
+	return etags
+}
+
+func (h *FileHandler) setFileHeaders(ctx *Context, name string) {
+	for _, rule := range h.cacheRules {
+		if rule.matches(name) {
+			ctx.ResponseWriter.Header().Set("Cache-Control", rule.Value)
+			break
+		}
+	}
+
+	if etag, ok := h.etags[name]; ok {
+		ctx.ResponseWriter.Header().Set("ETag", etag)
+	}
+
+	if overlay, ok := h.dir.(*OverlayFS); ok && overlay.Debug {
+		if layer := overlay.LayerOf(name); layer != "" {
+			ctx.ResponseWriter.Header().Set(OverlayLayerHeader, layer)
+		}
+	}
+}
+
+var precompressedEncodings = []struct {
+	encoding  string
+	extension string
+}{
+	{"br", ".br"},
+	{"gzip", ".gz"},
+}
+
+// returns true when a precompressed sibling was served
+func (h *FileHandler) servePrecompressed(ctx *Context, name string) bool {
+	if !h.precompressed {
+		return false
+	}
+	accepted := ctx.Request.Header.Get("Accept-Encoding")
+
+	for _, candidate := range precompressedEncodings {
+		if !acceptsEncoding(accepted, candidate.encoding) {
+			continue
+		}
+
+		file, err := h.dir.Open(name + candidate.extension)
+		if err != nil {
+			continue
+		}
+		stat, err := file.Stat()
+		if err != nil || stat.IsDir() {
+			file.Close()
+			continue
+		}
+		defer file.Close()
+
+		header := ctx.ResponseWriter.Header()
+		header.Set("Content-Encoding", candidate.encoding)
+		header.Add("Vary", "Accept-Encoding")
+		if etag := header.Get("ETag"); etag != "" {
+			header.Set("ETag", strings.TrimSuffix(etag, `"`)+"-"+candidate.encoding+`"`)
+		}
+		// the name of the original file decides the Content-Type
+		http.ServeContent(ctx.ResponseWriter, ctx.Request, name, stat.ModTime(), file)
+		return true
+	}
+
+	ctx.ResponseWriter.Header().Add("Vary", "Accept-Encoding")
+	return false
+}
+
+func acceptsEncoding(header, encoding string) bool {
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if !strings.EqualFold(strings.TrimSpace(name), encoding) {
+			continue
+		}
+		q := strings.ReplaceAll(strings.TrimSpace(params), " ", "")
+		return q != "q=0" && q != "q=0.0" && q != "q=0.00" && q != "q=0.000"
+	}
+	return false
+}
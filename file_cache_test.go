@@ -0,0 +1,74 @@
+package plaud
+
+import (
+	"embed"
+	"net/http"
+	"net/http/httptest"
+	"plaudern/utils"
+	"testing"
+	"testing/fstest"
+)
+
+//go:embed test_files
+var embeddedTestFiles embed.FS
+
+func TestStaticFilesPrecompressed(t *testing.T) {
+	fsys := fstest.MapFS{
+		"app.3f9a1c2b.js":    {Data: []byte("plain")},
+		"app.3f9a1c2b.js.br": {Data: []byte("brotli")},
+		"app.3f9a1c2b.js.gz": {Data: []byte("gzip")},
+		"index.html":         {Data: []byte("<html></html>")},
+	}
+
+	server := New(":8000")
+	testRouter := NewRouter("/")
+	testRouter.ServeFS("/static", fsys, WithPrecompressed(), WithCacheControl(FingerprintedCacheRule, HTMLCacheRule))
+	server.Register(testRouter)
+
+	tests := []struct {
+		path           string
+		acceptEncoding string
+		body           string
+		encoding       string
+		cacheControl   string
+	}{
+		{"/static/app.3f9a1c2b.js", "gzip, br", "brotli", "br", CacheImmutable},
+		{"/static/app.3f9a1c2b.js", "gzip, br;q=0", "gzip", "gzip", CacheImmutable},
+		{"/static/app.3f9a1c2b.js", "", "plain", "", CacheImmutable},
+		{"/static/", "gzip", "<html></html>", "", "no-cache"},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodGet, test.path, http.NoBody)
+		req.Header.Set("Accept-Encoding", test.acceptEncoding)
+		res := httptest.NewRecorder()
+		server.server.ServeHTTP(res, req)
+
+		utils.AssertEq(t, http.StatusOK, res.Code)
+		utils.AssertEq(t, test.body, res.Body.String())
+		utils.AssertEq(t, test.encoding, res.Header().Get("Content-Encoding"))
+		utils.AssertEq(t, test.cacheControl, res.Header().Get("Cache-Control"))
+	}
+}
+
+func TestStaticFilesEmbedETag(t *testing.T) {
+	server := New(":8000")
+	testRouter := NewRouter("/")
+	testRouter.ServeFS("/", embeddedTestFiles)
+	server.Register(testRouter)
+
+	req := httptest.NewRequest(http.MethodGet, "/test_files/css/style.css", http.NoBody)
+	res := httptest.NewRecorder()
+	server.server.ServeHTTP(res, req)
+
+	utils.AssertEq(t, http.StatusOK, res.Code)
+	etag := res.Header().Get("ETag")
+	utils.AssertNoEq(t, "", etag)
+
+	req = httptest.NewRequest(http.MethodGet, "/test_files/css/style.css", http.NoBody)
+	req.Header.Set("If-None-Match", etag)
+	res = httptest.NewRecorder()
+	server.server.ServeHTTP(res, req)
+
+	utils.AssertEq(t, http.StatusNotModified, res.Code)
+}
@@ -1,26 +1,87 @@
 package plaud
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
+	"maps"
 	"net/http"
+	"path"
+	"slices"
 	"strings"
 )
 
 type FileHandler struct {
 	handler     http.Handler
+	dir         http.FileSystem
 	path        string
 	middlewares []MiddleWareFunc
+	server      *Server
+	name        string
+	doc         RouteDoc
+	config      responseConfig
+	// path of the router the handler was created on
+	router string
+
+	// single page application index, served for unknown client side routes
+	spaIndex string
+	// api routes of the owning router, never answered by the spa fallback
+	apiRoutes []*http.ServeMux
+
+	// serve .br and .gz siblings to clients accepting them
+	precompressed bool
+	cacheRules    []CacheRule
+	// content hashes of embedded files, keyed by path
+	etags map[string]string
+
+	noListing   bool
+	denyHidden  bool
+	denyGlobs   []string
+	errorPages  map[int]string
+	slashPolicy TrailingSlashPolicy
+
+	// fingerprinted asset names
+	manifest *AssetManifest
+
+	writable *WriteOptions
+
+	archives       bool
+	archiveMaxSize int64
 }
 
-func NewFileHandler(dir http.FileSystem, path string) *FileHandler {
+// configures a FileHandler
+type FileHandlerOption func(*FileHandler)
+
+func NewFileHandler(dir http.FileSystem, path string, opts ...FileHandlerOption) *FileHandler {
 	if path == "" {
 		path = "/"
 	}
 
-	return &FileHandler{
+	h := &FileHandler{
 		handler: http.FileServer(dir),
+		dir:     dir,
 		path:    path,
 	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	if h.denyHidden || len(h.denyGlobs) > 0 {
+		h.handler = http.FileServer(listingFS{h})
+	}
+
+	return h
+}
+
+// serves the index file for paths without an extension that don't exist
+// so client side routes of a single page application resolve
+// missing assets (paths with an extension) still return 404
+func WithSPA(index string) FileHandlerOption {
+	return func(h *FileHandler) {
+		if index == "" {
+			index = "/index.html"
+		}
+		h.spaIndex = "/" + strings.TrimLeft(index, "/")
+	}
 }
 
 func (h *FileHandler) GetRoute() string {
@@ -31,27 +92,210 @@ func (h *FileHandler) GetRoute() string {
 	return h.path
 }
 
-// TODO: Should be used in a future commit
-// func (h *FileHandler) applyMiddleware(w http.ResponseWriter, r *http.Request) *Error {
-// 	for _, middleware := range h.middlewares {
-// 		if err := middleware(w, r); err != nil {
-// 			return err
-// 		}
-// 	}
-//
-// 	return nil
-// }
-
+// return the http handler for the directory
+// the router and handler middlewares run before the file server
 func (h *FileHandler) GetHandleFunc() func(http.ResponseWriter, *http.Request) {
+	fileServer := http.StripPrefix(strings.TrimRight(h.path, "/"), h.handler)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := NewContext(w, r)
+		ctx.server = h.server
+		ctx.config = h.config
+
+		runChain(ctx, h.middlewares, func(ctx *Context) *Error {
+			return h.serve(ctx, fileServer)
+		})
+	}
+}
+
+func (h *FileHandler) serve(ctx *Context, fileServer http.Handler) *Error {
+	name := h.relativePath(ctx.Request.URL.Path)
+	if h.isDenied(name) {
+		return h.fail(ctx, http.StatusForbidden)
+	}
+
+	if isWriteMethod(ctx.Request.Method) {
+		return h.serveWrite(ctx, name)
+	}
+
+	if h.manifest != nil {
+		if logical, ok := h.manifest.resolve(name); ok && !h.isDenied(logical) {
+			return h.serveFingerprinted(ctx, logical)
+		}
+	}
+
+	if h.shouldServeIndex(ctx.Request) {
+		return h.serveIndex(ctx)
+	}
+
+	stat, err := h.stat(name)
+	if err != nil {
+		return h.fail(ctx, http.StatusNotFound)
+	}
+	if stat.IsDir() {
+		if format := ctx.Request.URL.Query().Get("download"); h.archives && format != "" {
+			return h.serveArchive(ctx, name, format)
+		}
+
+		name = path.Join(name, "index.html")
+		if _, err := h.stat(name); err != nil && h.noListing {
+			return h.fail(ctx, http.StatusForbidden)
+		}
+	}
+
+	h.setFileHeaders(ctx, name)
+	if h.servePrecompressed(ctx, name) {
+		return nil
+	}
+
+	fileServer.ServeHTTP(ctx.ResponseWriter, ctx.Request)
 	return nil
 }
 
+// serves the file behind a fingerprinted name, the content never changes under that name
+func (h *FileHandler) serveFingerprinted(ctx *Context, name string) *Error {
+	file, err := h.dir.Open(name)
+	if err != nil {
+		return h.fail(ctx, http.StatusNotFound)
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil || stat.IsDir() {
+		return h.fail(ctx, http.StatusNotFound)
+	}
+
+	h.setFileHeaders(ctx, name)
+	ctx.ResponseWriter.Header().Set("Cache-Control", CacheImmutable)
+	if h.servePrecompressed(ctx, name) {
+		return nil
+	}
+
+	http.ServeContent(ctx.ResponseWriter, ctx.Request, name, stat.ModTime(), file)
+	return nil
+}
+
+// path of the request relative to the directory root
+func (h *FileHandler) relativePath(urlPath string) string {
+	name := strings.TrimPrefix(urlPath, strings.TrimRight(h.path, "/"))
+	return path.Clean("/" + name)
+}
+
+func (h *FileHandler) shouldServeIndex(r *http.Request) bool {
+	if h.spaIndex == "" || (r.Method != http.MethodGet && r.Method != http.MethodHead) {
+		return false
+	}
+
+	name := h.relativePath(r.URL.Path)
+	if path.Ext(name) != "" {
+		return false
+	}
+	if h.isAPIRoute(r) {
+		return false
+	}
+
+	file, err := h.dir.Open(name)
+	if err != nil {
+		return errors.Is(err, fs.ErrNotExist)
+	}
+	file.Close()
+	return false
+}
+
+func (h *FileHandler) serveIndex(ctx *Context) *Error {
+	file, err := h.dir.Open(h.spaIndex)
+	if err != nil {
+		return NewError("Not Found").SetCode(http.StatusNotFound)
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil || stat.IsDir() {
+		return NewError("Not Found").SetCode(http.StatusNotFound)
+	}
+
+	h.setFileHeaders(ctx, h.spaIndex)
+	http.ServeContent(ctx.ResponseWriter, ctx.Request, stat.Name(), stat.ModTime(), file)
+	return nil
+}
+
+// registers the paths of the api routes so the spa fallback never answers them
+// one mux per method, GET /items/{id} and POST /items/{key} would conflict in a shared one
+func (h *FileHandler) setAPIRoutes(routes []HTTPRoute) {
+	muxes := make(map[string]*http.ServeMux)
+	seen := make(map[string]bool)
+	noop := func(http.ResponseWriter, *http.Request) {}
+
+	for _, route := range routes {
+		method, pattern, ok := strings.Cut(route.GetRoute(), " ")
+		if !ok {
+			method, pattern = "", method
+		}
+		if pattern == "/" {
+			pattern = "/{$}"
+		}
+		if seen[method+" "+pattern] {
+			continue
+		}
+		seen[method+" "+pattern] = true
+
+		mux, ok := muxes[method]
+		if !ok {
+			mux = http.NewServeMux()
+			muxes[method] = mux
+		}
+		mux.HandleFunc(pattern, noop)
+	}
+
+	h.apiRoutes = slices.Collect(maps.Values(muxes))
+}
+
+// reports whether an api route of any method matches the path of r
+func (h *FileHandler) isAPIRoute(r *http.Request) bool {
+	for _, mux := range h.apiRoutes {
+		if _, pattern := mux.Handler(r); pattern != "" {
+			return true
+		}
+	}
+	return false
+}
+
 func (h *FileHandler) GetHandler() http.Handler {
 	return h.handler
 }
 
 func (h *FileHandler) stackMiddleware(middleware []MiddleWareFunc) {
-	h.middlewares = append(middleware, h.middlewares...)
+	h.middlewares = slices.Concat(middleware, h.middlewares)
+}
+
+func (h *FileHandler) Info() RouteInfo {
+	return RouteInfo{
+		Pattern:     h.GetRoute(),
+		Name:        h.name,
+		Handler:     "FileHandler",
+		Middlewares: middlewareNames(h.middlewares),
+		Router:      h.router,
+		Doc:         h.doc,
+		config:      h.config,
+	}
+}
+
+func (h *FileHandler) Name(name string) HTTPRoute {
+	h.name = name
+	return h
+}
+
+func (h *FileHandler) Describe(doc RouteDoc) HTTPRoute {
+	h.doc = doc.merge(h.doc)
+	return h
+}
+
+func (h *FileHandler) setServer(server *Server) {
+	h.server = server
+}
+
+func (h *FileHandler) inheritConfig(config responseConfig) {
+	h.config = h.config.inherit(config)
 }
 
 // registers a set of all middlewares
@@ -0,0 +1,114 @@
+package plaud
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"time"
+)
+
+// writes the file at the given path
+// Content-Type is detected from the extension, Range and If-Range requests are supported
+func (c *Context) File(name string) *Error {
+	file, err := os.Open(name)
+	if err != nil {
+		return openError(err)
+	}
+	defer file.Close()
+
+	return c.serveFile(file, "")
+}
+
+// writes the named file of the fs.FS, same as File
+func (c *Context) FileFS(fsys fs.FS, name string) *Error {
+	file, err := fsys.Open(name)
+	if err != nil {
+		return openError(err)
+	}
+	defer file.Close()
+
+	return c.serveFile(file, "")
+}
+
+// 404 for missing files, 403 for denied ones
+func openError(err error) *Error {
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return NewError("File not found").SetCode(http.StatusNotFound)
+	case errors.Is(err, fs.ErrPermission):
+		return NewError("Forbidden").SetCode(http.StatusForbidden).Wrap(err)
+	default:
+		return NewError("Failed to open file").SetCode(http.StatusInternalServerError).Wrap(err)
+	}
+}
+
+// attachment is the name the file is saved under, empty to display it
+// the Content-Disposition is only set once the file is known to be served
+func (c *Context) serveFile(file fs.File, attachment string) *Error {
+	stat, err := file.Stat()
+	if err != nil {
+		return openError(err)
+	}
+	if stat.IsDir() {
+		return NewError("File not found").SetCode(http.StatusNotFound)
+	}
+
+	content, ok := file.(io.ReadSeeker)
+	if !ok {
+		// ranges need seeking, buffer files of fs.FS implementations that can't
+		data, err := io.ReadAll(file)
+		if err != nil {
+			return NewError("Failed to read file").SetCode(http.StatusInternalServerError)
+		}
+		content = bytes.NewReader(data)
+	}
+
+	if attachment != "" {
+		c.setDisposition("attachment", attachment)
+	}
+	http.ServeContent(c.ResponseWriter, c.Request, stat.Name(), stat.ModTime(), content)
+	return nil
+}
+
+// writes the content to be displayed by the client
+// name is used for the Content-Type, modtime for conditional requests and can be zero
+func (c *Context) Content(name string, modtime time.Time, content io.ReadSeeker) {
+	http.ServeContent(c.ResponseWriter, c.Request, name, modtime, content)
+}
+
+// writes the content as a download saved under the given name
+func (c *Context) Attachment(name string, content io.ReadSeeker) {
+	c.setDisposition("attachment", name)
+	c.Content(name, time.Time{}, content)
+}
+
+// writes the file at the given path as a download saved under its base name
+func (c *Context) FileAttachment(name string) *Error {
+	file, err := os.Open(name)
+	if err != nil {
+		return openError(err)
+	}
+	defer file.Close()
+
+	return c.serveFile(file, path.Base(name))
+}
+
+func (c *Context) setDisposition(disposition, name string) {
+	value := mime.FormatMediaType(disposition, map[string]string{"filename": name})
+	if value == "" {
+		value = disposition
+	}
+	c.ResponseWriter.Header().Set("Content-Disposition", value)
+}
+
+// registers a GET route serving a single file
+func (r *Router) ServeFile(path string, file string) HTTPRoute {
+	return r.createRoute(GET, path, func(ctx *Context) (*Data, *Error) {
+		return nil, ctx.File(file)
+	})
+}
@@ -0,0 +1,102 @@
+package plaud
+
+import (
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"plaudern/utils"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFileResponses(t *testing.T) {
+	const testFile string = "./test_files/index.html"
+	fileContent, err := os.ReadFile(testFile)
+	utils.AssertNoErr(t, err)
+
+	server := New(":8000")
+	testRouter := NewRouter("/")
+	testRouter.ServeFile("/index", testFile)
+	testRouter.Get("/missing", func(ctx *Context) (*Data, *Error) {
+		return nil, ctx.File("./test_files/missing.html")
+	})
+	testRouter.Get("/missing-download", func(ctx *Context) (*Data, *Error) {
+		return nil, ctx.FileAttachment("./test_files/nope.csv")
+	})
+	testRouter.Get("/download", func(ctx *Context) (*Data, *Error) {
+		return nil, ctx.FileAttachment(testFile)
+	})
+	testRouter.Get("/denied", func(ctx *Context) (*Data, *Error) {
+		return nil, ctx.FileFS(deniedFS{}, "secret.txt")
+	})
+	testRouter.Get("/report", func(ctx *Context) (*Data, *Error) {
+		ctx.Attachment("résumé 2024.csv", strings.NewReader("id,name\n1,jotaro\n"))
+		return nil, nil
+	})
+	server.Register(testRouter)
+
+	req := httptest.NewRequest(http.MethodGet, "/index", http.NoBody)
+	res := httptest.NewRecorder()
+	server.server.ServeHTTP(res, req)
+	utils.AssertEq(t, http.StatusOK, res.Code)
+	utils.AssertEq(t, string(fileContent), res.Body.String())
+	utils.AssertEq(t, "text/html; charset=utf-8", res.Header().Get("Content-Type"))
+
+	// resumable download
+	req = httptest.NewRequest(http.MethodGet, "/index", http.NoBody)
+	req.Header.Set("Range", "bytes=0-4")
+	res = httptest.NewRecorder()
+	server.server.ServeHTTP(res, req)
+	utils.AssertEq(t, http.StatusPartialContent, res.Code)
+	utils.AssertEq(t, string(fileContent[:5]), res.Body.String())
+
+	// a stale If-Range returns the whole file
+	req = httptest.NewRequest(http.MethodGet, "/index", http.NoBody)
+	req.Header.Set("Range", "bytes=0-4")
+	req.Header.Set("If-Range", time.Unix(0, 0).UTC().Format(http.TimeFormat))
+	res = httptest.NewRecorder()
+	server.server.ServeHTTP(res, req)
+	utils.AssertEq(t, http.StatusOK, res.Code)
+	utils.AssertEq(t, string(fileContent), res.Body.String())
+
+	req = httptest.NewRequest(http.MethodGet, "/missing", http.NoBody)
+	res = httptest.NewRecorder()
+	server.server.ServeHTTP(res, req)
+	utils.AssertEq(t, http.StatusNotFound, res.Code)
+
+	// errors are not saved as downloads
+	req = httptest.NewRequest(http.MethodGet, "/missing-download", http.NoBody)
+	res = httptest.NewRecorder()
+	server.server.ServeHTTP(res, req)
+	utils.AssertEq(t, http.StatusNotFound, res.Code)
+	utils.AssertEq(t, "", res.Header().Get("Content-Disposition"))
+
+	req = httptest.NewRequest(http.MethodGet, "/download", http.NoBody)
+	res = httptest.NewRecorder()
+	server.server.ServeHTTP(res, req)
+	utils.AssertEq(t, http.StatusOK, res.Code)
+	utils.AssertEq(t, "attachment; filename=index.html", res.Header().Get("Content-Disposition"))
+
+	req = httptest.NewRequest(http.MethodGet, "/denied", http.NoBody)
+	res = httptest.NewRecorder()
+	server.server.ServeHTTP(res, req)
+	utils.AssertEq(t, http.StatusForbidden, res.Code)
+
+	req = httptest.NewRequest(http.MethodGet, "/report", http.NoBody)
+	req.Header.Set("Range", "bytes=8-")
+	res = httptest.NewRecorder()
+	server.server.ServeHTTP(res, req)
+	utils.AssertEq(t, http.StatusPartialContent, res.Code)
+	utils.AssertEq(t, "1,jotaro\n", res.Body.String())
+	utils.AssertEq(t, "attachment; filename*=utf-8''r%C3%A9sum%C3%A9%202024.csv", res.Header().Get("Content-Disposition"))
+	utils.AssertEq(t, "text/csv; charset=utf-8", res.Header().Get("Content-Type"))
+}
+
+// denies every file
+type deniedFS struct{}
+
+func (deniedFS) Open(name string) (fs.File, error) {
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrPermission}
+}
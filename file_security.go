@@ -0,0 +1,169 @@
+package plaud
+
+import (
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+)
+
+// how a FileHandler mounted at /prefix/ answers requests to /prefix
+type TrailingSlashPolicy int
+
+const (
+	// redirects /prefix to /prefix/, the default
+	RedirectTrailingSlash TrailingSlashPolicy = iota
+	// serves /prefix the same as /prefix/
+	ServeWithoutTrailingSlash
+	// answers /prefix with 404
+	StrictTrailingSlash
+)
+
+// directories without an index.html answer 403 instead of listing their contents
+func WithoutDirectoryListing() FileHandlerOption {
+	return func(h *FileHandler) {
+		h.noListing = true
+	}
+}
+
+// denies every path with a segment starting with '.' like .env or .git/config
+func WithDenyHidden() FileHandlerOption {
+	return func(h *FileHandler) {
+		h.denyHidden = true
+	}
+}
+
+// denies paths matching any of the globs, see path.Match
+// globs are matched against every segment and the whole path without the leading '/'
+func WithDeny(globs ...string) FileHandlerOption {
+	return func(h *FileHandler) {
+		h.denyGlobs = append(h.denyGlobs, globs...)
+	}
+}
+
+// serves a file of the directory instead of the Error JSON for the status code
+// e.g. WithErrorPage(http.StatusNotFound, "/404.html")
+func WithErrorPage(code int, page string) FileHandlerOption {
+	return func(h *FileHandler) {
+		if h.errorPages == nil {
+			h.errorPages = make(map[int]string)
+		}
+		h.errorPages[code] = "/" + strings.TrimLeft(page, "/")
+	}
+}
+
+func WithTrailingSlash(policy TrailingSlashPolicy) FileHandlerOption {
+	return func(h *FileHandler) {
+		h.slashPolicy = policy
+	}
+}
+
+func (h *FileHandler) isDenied(name string) bool {
+	trimmed := strings.TrimPrefix(name, "/")
+	for _, glob := range h.denyGlobs {
+		if matched, _ := path.Match(glob, trimmed); matched {
+			return true
+		}
+	}
+
+	for _, segment := range strings.Split(trimmed, "/") {
+		if h.denyHidden && strings.HasPrefix(segment, ".") {
+			return true
+		}
+		for _, glob := range h.denyGlobs {
+			if matched, _ := path.Match(glob, segment); matched {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// the directory of a FileHandler with deny rules
+// keeps the denied files out of the directory listings
+type listingFS struct {
+	h *FileHandler
+}
+
+func (d listingFS) Open(name string) (http.File, error) {
+	file, err := d.h.dir.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return listingDir{File: file, h: d.h, name: name}, nil
+}
+
+type listingDir struct {
+	http.File
+	h    *FileHandler
+	name string
+}
+
+func (d listingDir) Readdir(count int) ([]fs.FileInfo, error) {
+	for {
+		entries, err := d.File.Readdir(count)
+		visible := slices.DeleteFunc(entries, func(entry fs.FileInfo) bool {
+			return d.h.isDenied(path.Join(d.name, entry.Name()))
+		})
+		// a page of only denied entries isn't the end of the directory
+		if len(visible) > 0 || len(entries) == 0 || count <= 0 || err != nil {
+			return visible, err
+		}
+	}
+}
+
+func (h *FileHandler) stat(name string) (fs.FileInfo, error) {
+	file, err := h.dir.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return file.Stat()
+}
+
+// answers with the configured error page, or the Error JSON
+func (h *FileHandler) fail(ctx *Context, code int) *Error {
+	if page, ok := h.errorPages[code]; ok && !h.isDenied(page) {
+		if file, err := h.dir.Open(page); err == nil {
+			defer file.Close()
+
+			contentType := mime.TypeByExtension(path.Ext(page))
+			if contentType == "" {
+				contentType = "text/html; charset=utf-8"
+			}
+			ctx.ResponseWriter.Header().Set("Content-Type", contentType)
+			ctx.Status(code)
+			if ctx.Request.Method != http.MethodHead {
+				_, _ = io.Copy(ctx.ResponseWriter, file)
+			}
+			return nil
+		}
+	}
+
+	return NewError(http.StatusText(code)).SetCode(code)
+}
+
+// pattern and handler for /prefix when the policy doesn't leave it to the mux
+func (h *FileHandler) bareHandleFunc() (string, func(http.ResponseWriter, *http.Request)) {
+	if h.path == "/" || h.slashPolicy == RedirectTrailingSlash {
+		return "", nil
+	}
+
+	bare := strings.TrimRight(h.path, "/")
+	if h.slashPolicy == ServeWithoutTrailingSlash {
+		return bare, h.GetHandleFunc()
+	}
+
+	return bare, func(w http.ResponseWriter, r *http.Request) {
+		ctx := NewContext(w, r)
+		ctx.server = h.server
+		ctx.config = h.config
+
+		runChain(ctx, h.middlewares, func(ctx *Context) *Error {
+			return h.fail(ctx, http.StatusNotFound)
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package plaud
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"plaudern/utils"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestStaticFilesHardening(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html":       {Data: []byte("index")},
+		".env":             {Data: []byte("SECRET=1")},
+		".git/config":      {Data: []byte("[core]")},
+		"backup.sql":       {Data: []byte("DROP TABLE")},
+		"reports/data.csv": {Data: []byte("a,b")},
+		"reports/.draft":   {Data: []byte("a")},
+		"reports/old.sql":  {Data: []byte("DROP TABLE")},
+		"404.html":         {Data: []byte("not here")},
+	}
+
+	server := New(":8000")
+	testRouter := NewRouter("/")
+	testRouter.ServeFS("/files", fsys,
+		WithoutDirectoryListing(),
+		WithDenyHidden(),
+		WithDeny("*.sql"),
+		WithErrorPage(http.StatusNotFound, "404.html"),
+		WithTrailingSlash(ServeWithoutTrailingSlash),
+	)
+	testRouter.ServeFS("/strict", fsys, WithTrailingSlash(StrictTrailingSlash))
+	testRouter.ServeFS("/listed", fsys, WithDenyHidden(), WithDeny("*.sql"))
+	server.Register(testRouter)
+
+	tests := []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/files/", http.StatusOK, "index"},
+		{"/files", http.StatusOK, "index"},
+		{"/files/.env", http.StatusForbidden, ""},
+		{"/files/.git/config", http.StatusForbidden, ""},
+		{"/files/backup.sql", http.StatusForbidden, ""},
+		{"/files/reports/", http.StatusForbidden, ""},
+		{"/files/reports/data.csv", http.StatusOK, "a,b"},
+		{"/files/missing.txt", http.StatusNotFound, "not here"},
+		{"/strict/", http.StatusOK, "index"},
+		{"/strict", http.StatusNotFound, ""},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodGet, test.path, http.NoBody)
+		res := httptest.NewRecorder()
+		server.server.ServeHTTP(res, req)
+
+		if res.Code != test.code {
+			t.Fatalf("%s Expected: %v Got: %v", test.path, test.code, res.Code)
+		}
+		if test.body != "" {
+			utils.AssertEq(t, test.body, res.Body.String())
+		}
+	}
+
+	// denied files are left out of the listing
+	req := httptest.NewRequest(http.MethodGet, "/listed/reports/", http.NoBody)
+	res := httptest.NewRecorder()
+	server.server.ServeHTTP(res, req)
+	utils.AssertEq(t, http.StatusOK, res.Code)
+	utils.AssertEq(t, true, strings.Contains(res.Body.String(), "data.csv"))
+	utils.AssertEq(t, false, strings.Contains(res.Body.String(), ".draft"))
+	utils.AssertEq(t, false, strings.Contains(res.Body.String(), "old.sql"))
+}
@@ -0,0 +1,179 @@
+package plaud
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// non standard method creating a directory, borrowed from WebDAV
+const MethodMkcol = "MKCOL"
+
+type WriteOptions struct {
+	// largest accepted upload in bytes, 0 means unlimited
+	MaxSize int64
+	// allows DELETE
+	Delete bool
+	// allows MKCOL
+	Mkdir bool
+}
+
+// makes the directory writable: PUT uploads or replaces a file,
+// DELETE removes a file or an empty directory and MKCOL creates a directory
+// only works for handlers serving a http.Dir, every write runs through the middleware chain
+func WithWritable(opts WriteOptions) FileHandlerOption {
+	return func(h *FileHandler) {
+		h.writable = &opts
+	}
+}
+
+func isWriteMethod(method string) bool {
+	return method == http.MethodPut || method == http.MethodDelete || method == MethodMkcol
+}
+
+func (h *FileHandler) serveWrite(ctx *Context, name string) *Error {
+	root, ok := h.dir.(http.Dir)
+	if h.writable == nil || !ok {
+		return NewError("Method Not Allowed").SetCode(http.StatusMethodNotAllowed)
+	}
+
+	target, err := resolveInRoot(string(root), name)
+	if err != nil || target == "" {
+		return NewError("Forbidden").SetCode(http.StatusForbidden)
+	}
+
+	switch ctx.Request.Method {
+	case http.MethodPut:
+		return h.upload(ctx, target)
+	case http.MethodDelete:
+		if !h.writable.Delete {
+			return NewError("Method Not Allowed").SetCode(http.StatusMethodNotAllowed)
+		}
+		return h.remove(ctx, target)
+	default:
+		if !h.writable.Mkdir {
+			return NewError("Method Not Allowed").SetCode(http.StatusMethodNotAllowed)
+		}
+		return h.mkdir(ctx, target)
+	}
+}
+
+// returns the path of name on disk, confined to root
+// symlinks pointing outside of the root are rejected
+// the root itself resolves to ""
+func resolveInRoot(root, name string) (string, error) {
+	local, err := filepath.Localize(strings.Trim(name, "/"))
+	if err != nil {
+		return "", err
+	}
+	if local == "." {
+		return "", nil
+	}
+
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return "", err
+	}
+	target := filepath.Join(realRoot, local)
+
+	// the parent has to exist for any write, resolve it to catch symlinks
+	realParent, err := filepath.EvalSymlinks(filepath.Dir(target))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return target, nil
+		}
+		return "", err
+	}
+	if rel, err := filepath.Rel(realRoot, realParent); err != nil || !filepath.IsLocal(rel) {
+		return "", errors.New("path escapes the root")
+	}
+
+	if info, err := os.Lstat(target); err == nil && info.Mode()&fs.ModeSymlink != 0 {
+		return "", errors.New("refusing to write through a symlink")
+	}
+
+	return target, nil
+}
+
+// writes the body to a temporary file next to the target and renames it into place
+func (h *FileHandler) upload(ctx *Context, target string) *Error {
+	if info, err := os.Stat(filepath.Dir(target)); err != nil || !info.IsDir() {
+		return NewError("Parent directory does not exist").SetCode(http.StatusConflict)
+	}
+	info, err := os.Stat(target)
+	if err == nil && info.IsDir() {
+		return NewError("Target is a directory").SetCode(http.StatusConflict)
+	}
+	created := err != nil
+
+	body := io.Reader(ctx.Request.Body)
+	if h.writable.MaxSize > 0 {
+		if ctx.Request.ContentLength > h.writable.MaxSize {
+			return NewError("File too large").SetCode(http.StatusRequestEntityTooLarge)
+		}
+		body = http.MaxBytesReader(ctx.ResponseWriter, ctx.Request.Body, h.writable.MaxSize)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(target), ".upload-*")
+	if err != nil {
+		return NewError("Failed to store file").SetCode(http.StatusInternalServerError)
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = io.Copy(tmp, body)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return NewError("File too large").SetCode(http.StatusRequestEntityTooLarge)
+		}
+		return NewError("Failed to store file").SetCode(http.StatusInternalServerError)
+	}
+
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return NewError("Failed to store file").SetCode(http.StatusInternalServerError)
+	}
+	if err := os.Rename(tmp.Name(), target); err != nil {
+		return NewError("Failed to store file").SetCode(http.StatusInternalServerError)
+	}
+
+	if created {
+		ctx.Status(http.StatusCreated)
+	} else {
+		ctx.Status(http.StatusNoContent)
+	}
+	return nil
+}
+
+func (h *FileHandler) remove(ctx *Context, target string) *Error {
+	if _, err := os.Lstat(target); err != nil {
+		return NewError("Not Found").SetCode(http.StatusNotFound)
+	}
+	if err := os.Remove(target); err != nil {
+		return NewError("Directory is not empty").SetCode(http.StatusConflict)
+	}
+
+	ctx.Status(http.StatusNoContent)
+	return nil
+}
+
+func (h *FileHandler) mkdir(ctx *Context, target string) *Error {
+	if _, err := os.Lstat(target); err == nil {
+		return NewError("Already exists").SetCode(http.StatusMethodNotAllowed)
+	}
+	if err := os.Mkdir(target, 0o755); err != nil {
+		return NewError("Parent directory does not exist").SetCode(http.StatusConflict)
+	}
+
+	ctx.Status(http.StatusCreated)
+	return nil
+}
@@ -0,0 +1,81 @@
+package plaud
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"plaudern/utils"
+	"strings"
+	"testing"
+)
+
+func TestWritableStaticFiles(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	utils.AssertNoErr(t, os.Symlink(outside, filepath.Join(root, "outside")))
+
+	server := New(":8000")
+	testRouter := NewRouter("/")
+	testRouter.ServeDir("/artifacts", http.Dir(root), WithWritable(WriteOptions{MaxSize: 16, Delete: true, Mkdir: true})).
+		Use(func(ctx *Context) *Error {
+			if ctx.Request.Method != http.MethodGet && ctx.Request.Header.Get("Authorization") != "token" {
+				return ctx.AbortWithError("Unauthorized", http.StatusUnauthorized)
+			}
+			return nil
+		})
+	testRouter.ServeDir("/readonly", http.Dir(root))
+	server.Register(testRouter)
+
+	tests := []struct {
+		method string
+		path   string
+		body   string
+		auth   bool
+		code   int
+	}{
+		{http.MethodPut, "/artifacts/build.log", "ok", false, http.StatusUnauthorized},
+		{http.MethodPut, "/artifacts/build.log", "ok", true, http.StatusCreated},
+		{http.MethodPut, "/artifacts/build.log", "replaced", true, http.StatusNoContent},
+		{http.MethodPut, "/artifacts/big.bin", strings.Repeat("x", 17), true, http.StatusRequestEntityTooLarge},
+		{http.MethodPut, "/artifacts/missing/build.log", "ok", true, http.StatusConflict},
+		{http.MethodPut, "/artifacts/outside/escape.txt", "ok", true, http.StatusForbidden},
+		{MethodMkcol, "/artifacts/reports", "", true, http.StatusCreated},
+		{MethodMkcol, "/artifacts/reports", "", true, http.StatusMethodNotAllowed},
+		{http.MethodPut, "/artifacts/reports/a.txt", "a", true, http.StatusCreated},
+		{http.MethodDelete, "/artifacts/reports", "", true, http.StatusConflict},
+		{http.MethodDelete, "/artifacts/reports/a.txt", "", true, http.StatusNoContent},
+		{http.MethodDelete, "/artifacts/reports/a.txt", "", true, http.StatusNotFound},
+		{http.MethodPut, "/readonly/build.log", "ok", true, http.StatusMethodNotAllowed},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest(test.method, test.path, bytes.NewBufferString(test.body))
+		if test.auth {
+			req.Header.Set("Authorization", "token")
+		}
+		res := httptest.NewRecorder()
+		server.server.ServeHTTP(res, req)
+
+		if res.Code != test.code {
+			t.Fatalf("%s %s Expected: %v Got: %v", test.method, test.path, test.code, res.Code)
+		}
+	}
+
+	content, err := os.ReadFile(filepath.Join(root, "build.log"))
+	utils.AssertNoErr(t, err)
+	utils.AssertEq(t, "replaced", string(content))
+
+	// symlinks can't be used to escape the root
+	_, err = os.Stat(filepath.Join(outside, "escape.txt"))
+	utils.AssertNoEq(t, nil, err)
+
+	entries, err := os.ReadDir(root)
+	utils.AssertNoErr(t, err)
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".upload-") {
+			t.Fatalf("Temporary file %s was left behind", entry.Name())
+		}
+	}
+}
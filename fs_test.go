@@ -2,11 +2,13 @@ package plaud
 
 import (
 	"context"
+	"encoding/json"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"plaudern/utils"
+	"strings"
 	"testing"
 )
 
@@ -53,3 +55,85 @@ func TestNestedRouterStaticFiles(t *testing.T) {
 	utils.AssertNoErr(t, err)
 	utils.AssertEq(t, res.Body.String(), string(fileContent))
 }
+
+func TestStaticFilesMiddleware(t *testing.T) {
+	const testFolder string = "./test_files"
+	var visited []string
+
+	server := New(":8000")
+	testRouter := NewRouter("/").Use(func(_ *Context) *Error {
+		visited = append(visited, "router")
+		return nil
+	})
+	testRouter.ServeDir("/public", http.Dir(testFolder))
+	testRouter.ServeDir("/private", http.Dir(testFolder)).Use(func(ctx *Context) *Error {
+		visited = append(visited, "handler")
+		return ctx.AbortWithError("Unauthorized", http.StatusUnauthorized)
+	})
+	server.Register(testRouter)
+
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodGet, "/public/", io.Reader(nil))
+	res := httptest.NewRecorder()
+	server.server.ServeHTTP(res, req)
+	utils.AssertEq(t, http.StatusOK, res.Code)
+
+	req = httptest.NewRequestWithContext(context.Background(), http.MethodGet, "/private/", io.Reader(nil))
+	res = httptest.NewRecorder()
+	server.server.ServeHTTP(res, req)
+	utils.AssertEq(t, http.StatusUnauthorized, res.Code)
+
+	fileErr := &Error{}
+	utils.AssertNoErr(t, json.NewDecoder(res.Body).Decode(fileErr))
+	utils.AssertEq(t, "Unauthorized", fileErr.Message)
+	utils.AssertEq(t, "router,router,handler", strings.Join(visited, ","))
+}
+
+func TestStaticFilesSPA(t *testing.T) {
+	const testFolder string = "./test_files"
+	server := New(":8000")
+	testRouter := NewRouter("/app")
+	testRouter.Post("/api/users", func(_ *Context) (*Data, *Error) {
+		return NewData("users"), nil
+	})
+	// same path with different wildcards per method
+	testRouter.Get("/items/{id}", func(ctx *Context) (*Data, *Error) {
+		return NewData(ctx.Request.PathValue("id")), nil
+	})
+	testRouter.Post("/items/{key}", func(ctx *Context) (*Data, *Error) {
+		return NewData(ctx.Request.PathValue("key")), nil
+	})
+	testRouter.Post("/orders/{key}", func(ctx *Context) (*Data, *Error) {
+		return NewData(ctx.Request.PathValue("key")), nil
+	})
+	testRouter.ServeDir("/", http.Dir(testFolder), WithSPA("index.html"))
+	server.Register(testRouter)
+
+	indexContent, err := os.ReadFile(testFolder + "/index.html")
+	utils.AssertNoErr(t, err)
+	nestedContent, err := os.ReadFile(testFolder + "/nested/index.html")
+	utils.AssertNoErr(t, err)
+
+	tests := []struct {
+		method string
+		path   string
+		code   int
+		body   string
+	}{
+		{http.MethodGet, "/app/settings/profile", http.StatusOK, string(indexContent)},
+		{http.MethodGet, "/app/nested/", http.StatusOK, string(nestedContent)},
+		{http.MethodGet, "/app/js/missing.js", http.StatusNotFound, ""},
+		{http.MethodGet, "/app/api/users", http.StatusNotFound, ""},
+		{http.MethodPost, "/app/items/7", http.StatusOK, ""},
+		{http.MethodGet, "/app/orders/7", http.StatusNotFound, ""},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequestWithContext(context.Background(), test.method, test.path, io.Reader(nil))
+		res := httptest.NewRecorder()
+		server.server.ServeHTTP(res, req)
+		utils.AssertEq(t, test.code, res.Code)
+		if test.body != "" {
+			utils.AssertEq(t, test.body, res.Body.String())
+		}
+	}
+}
@@ -0,0 +1,284 @@
+package plaud
+
+import (
+	"net/http"
+	"sync"
+)
+
+// what happens when a subscriber's queue is full
+type SlowConsumerPolicy int
+
+const (
+	// the new message is dropped for that subscriber
+	DropNewest SlowConsumerPolicy = iota
+	// the oldest queued message is dropped to make room
+	DropOldest
+	// the subscriber is disconnected
+	Disconnect
+)
+
+const defaultSubscriberBuffer = 64
+
+// a message published to a topic
+type Message struct {
+	Topic string
+	Event string
+	Data  []byte
+}
+
+type SubscribeOptions struct {
+	// size of the subscriber's queue, defaults to 64
+	Buffer int
+	Policy SlowConsumerPolicy
+}
+
+// in-process pub/sub hub
+// event streams and websockets subscribe to topics and receive everything published to them
+type Hub struct {
+	mu     sync.RWMutex
+	topics map[string]map[*Subscription]struct{}
+	closed bool
+}
+
+func NewHub() *Hub {
+	return &Hub{
+		topics: make(map[string]map[*Subscription]struct{}),
+	}
+}
+
+// a subscriber of a single topic
+type Subscription struct {
+	hub    *Hub
+	topic  string
+	policy SlowConsumerPolicy
+
+	mu       sync.Mutex
+	messages chan Message
+	closed   bool
+	done     chan struct{}
+}
+
+// subscribes to the topic
+// the subscription should be closed once the subscriber is gone
+func (h *Hub) Subscribe(topic string, opts SubscribeOptions) *Subscription {
+	if opts.Buffer <= 0 {
+		opts.Buffer = defaultSubscriberBuffer
+	}
+	sub := &Subscription{
+		hub:      h,
+		topic:    topic,
+		policy:   opts.Policy,
+		messages: make(chan Message, opts.Buffer),
+		done:     make(chan struct{}),
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		sub.closeQueue()
+		return sub
+	}
+	if h.topics[topic] == nil {
+		h.topics[topic] = make(map[*Subscription]struct{})
+	}
+	h.topics[topic][sub] = struct{}{}
+
+	return sub
+}
+
+// publishes the message to every subscriber of the topic
+// returns the number of subscribers the message was queued for
+func (h *Hub) Publish(topic string, event string, data []byte) int {
+	msg := Message{Topic: topic, Event: event, Data: data}
+
+	var (
+		delivered int
+		slow      []*Subscription
+	)
+
+	h.mu.RLock()
+	for sub := range h.topics[topic] {
+		ok, disconnect := sub.deliver(msg)
+		if ok {
+			delivered++
+		}
+		if disconnect {
+			slow = append(slow, sub)
+		}
+	}
+	h.mu.RUnlock()
+
+	for _, sub := range slow {
+		sub.Close()
+	}
+
+	return delivered
+}
+
+// number of subscribers of the topic
+func (h *Hub) Presence(topic string) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.topics[topic])
+}
+
+// topics with at least one subscriber
+func (h *Hub) Topics() []string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	topics := make([]string, 0, len(h.topics))
+	for topic := range h.topics {
+		topics = append(topics, topic)
+	}
+	return topics
+}
+
+// closes every subscription, later subscriptions are closed immediately
+func (h *Hub) Close() {
+	h.mu.Lock()
+	topics := h.topics
+	h.topics = make(map[string]map[*Subscription]struct{})
+	h.closed = true
+	h.mu.Unlock()
+
+	for _, subs := range topics {
+		for sub := range subs {
+			sub.closeQueue()
+		}
+	}
+}
+
+func (h *Hub) remove(sub *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	subs := h.topics[sub.topic]
+	delete(subs, sub)
+	if len(subs) == 0 {
+		delete(h.topics, sub.topic)
+	}
+}
+
+func (s *Subscription) Topic() string {
+	return s.topic
+}
+
+// queued messages, closed once the subscription ends
+func (s *Subscription) Messages() <-chan Message {
+	return s.messages
+}
+
+// closed once the subscription ends
+func (s *Subscription) Done() <-chan struct{} {
+	return s.done
+}
+
+// unsubscribes from the hub
+func (s *Subscription) Close() {
+	s.hub.remove(s)
+	s.closeQueue()
+}
+
+func (s *Subscription) closeQueue() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.closed {
+		s.closed = true
+		close(s.done)
+		close(s.messages)
+	}
+}
+
+// returns whether the message was queued and whether the subscriber should be disconnected
+func (s *Subscription) deliver(msg Message) (bool, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return false, false
+	}
+
+	select {
+	case s.messages <- msg:
+		return true, false
+	default:
+	}
+
+	switch s.policy {
+	case DropOldest:
+		select {
+		case <-s.messages:
+		default:
+		}
+		select {
+		case s.messages <- msg:
+			return true, false
+		default:
+			return false, false
+		}
+	case Disconnect:
+		return false, true
+	default:
+		return false, false
+	}
+}
+
+// the hub of the server the route is registered with
+// nil when the route wasn't registered through a Server
+func (c *Context) Hub() *Hub {
+	if c.server == nil {
+		return nil
+	}
+	return c.server.hub
+}
+
+// subscribes to a topic of the server's hub for the lifetime of the request
+func (c *Context) Subscribe(topic string, opts SubscribeOptions) (*Subscription, *Error) {
+	hub := c.Hub()
+	if hub == nil {
+		return nil, NewError("No hub available").SetCode(http.StatusInternalServerError)
+	}
+
+	sub := hub.Subscribe(topic, opts)
+	go func() {
+		select {
+		case <-c.Request.Context().Done():
+			sub.Close()
+		case <-sub.Done():
+		}
+	}()
+
+	return sub, nil
+}
+
+// sends every message of the subscription as an event until either side closes
+func (s *EventStream) Pipe(sub *Subscription) error {
+	for {
+		select {
+		case msg, ok := <-sub.Messages():
+			if !ok {
+				return nil
+			}
+			if err := s.Send(Event{Event: msg.Event, Data: string(msg.Data)}); err != nil {
+				return err
+			}
+		case <-s.Done():
+			return nil
+		}
+	}
+}
+
+// sends every message of the subscription as a text message until either side closes
+func (ws *WebSocketConn) Pipe(sub *Subscription) error {
+	for {
+		select {
+		case msg, ok := <-sub.Messages():
+			if !ok {
+				return nil
+			}
+			if err := ws.WriteMessage(TextMessage, msg.Data); err != nil {
+				return err
+			}
+		case <-ws.Done():
+			return nil
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package plaud
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"plaudern/utils"
+	"testing"
+)
+
+func TestHubSlowConsumerPolicies(t *testing.T) {
+	hub := NewHub()
+	newest := hub.Subscribe("room", SubscribeOptions{Buffer: 1, Policy: DropNewest})
+	oldest := hub.Subscribe("room", SubscribeOptions{Buffer: 1, Policy: DropOldest})
+	slow := hub.Subscribe("room", SubscribeOptions{Buffer: 1, Policy: Disconnect})
+	utils.AssertEq(t, 3, hub.Presence("room"))
+
+	utils.AssertEq(t, 3, hub.Publish("room", "", []byte("first")))
+	utils.AssertEq(t, 1, hub.Publish("room", "", []byte("second")))
+
+	utils.AssertEq(t, "first", string((<-newest.Messages()).Data))
+	utils.AssertEq(t, "second", string((<-oldest.Messages()).Data))
+
+	<-slow.Done()
+	utils.AssertEq(t, 2, hub.Presence("room"))
+
+	hub.Close()
+	if _, ok := <-newest.Messages(); ok {
+		t.Fatal("Subscription was not closed with the hub")
+	}
+	utils.AssertEq(t, 0, hub.Presence("room"))
+}
+
+func TestContextSubscribe(t *testing.T) {
+	server := New(":8000")
+	testRouter := NewRouter("/")
+	testRouter.SSE("/events", func(ctx *Context, stream *EventStream) error {
+		sub, err := ctx.Subscribe("news", SubscribeOptions{})
+		if err != nil {
+			return err
+		}
+		ctx.Hub().Publish("news", "headline", []byte("hello"))
+		sub.Close()
+		return stream.Pipe(sub)
+	})
+	server.Register(testRouter)
+
+	req := httptest.NewRequest(http.MethodGet, "/events", http.NoBody)
+	res := httptest.NewRecorder()
+	server.server.ServeHTTP(res, req)
+
+	utils.AssertEq(t, "event: headline\ndata: hello\n\n", res.Body.String())
+	utils.AssertEq(t, 0, server.Hub().Presence("news"))
+}
@@ -0,0 +1,94 @@
+package plaud
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"runtime"
+	"strings"
+	"text/tabwriter"
+)
+
+// metadata of a registered route
+type RouteInfo struct {
+	// empty for file handlers, which answer every method
+	Method  string `json:"method,omitempty"`
+	Pattern string `json:"pattern"`
+	Name    string `json:"name,omitempty"`
+	// name of the handler function
+	Handler string `json:"handler"`
+	// names of the middlewares in execution order
+	Middlewares []string `json:"middlewares"`
+	// path of the router the route was created on
+	Router string   `json:"router"`
+	Doc    RouteDoc `json:"-"`
+
+	// response settings of the route, completed by the server's
+	config responseConfig
+}
+
+// describes every route registered with the server
+func (s *Server) Routes() []RouteInfo {
+	routes := make([]RouteInfo, 0, len(s.routes))
+	for _, route := range s.routes {
+		info := route.Info()
+		info.config = info.config.inherit(s.config)
+		routes = append(routes, info)
+	}
+	return routes
+}
+
+// writes the routes as an aligned table
+func WriteRouteTable(w io.Writer, routes []RouteInfo) error {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "METHOD\tPATTERN\tNAME\tHANDLER\tMIDDLEWARES\tROUTER")
+	for _, route := range routes {
+		method := route.Method
+		if method == "" {
+			method = "*"
+		}
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			method, route.Pattern, route.Name, route.Handler, strings.Join(route.Middlewares, ","), route.Router)
+	}
+	return table.Flush()
+}
+
+// handler listing the routes of the server
+// answers JSON, or a plain text table with ?format=table
+//
+//	router.Get("/debug/routes", server.RoutesFunc())
+func (s *Server) RoutesFunc() HTTPFunc {
+	return func(ctx *Context) (*Data, *Error) {
+		routes := s.Routes()
+		if ctx.Request.URL.Query().Get("format") != "table" {
+			return NewData("Routes").SetData(routes), nil
+		}
+
+		ctx.Header("Content-Type", "text/plain; charset=utf-8")
+		ctx.Status(http.StatusOK)
+		if err := WriteRouteTable(ctx.ResponseWriter, routes); err != nil {
+			return nil, NewError("Failed to write route table").SetCode(http.StatusInternalServerError)
+		}
+		return nil, nil
+	}
+}
+
+func funcName(fn any) string {
+	value := reflect.ValueOf(fn)
+	if !value.IsValid() || value.Kind() != reflect.Func || value.IsNil() {
+		return ""
+	}
+	if f := runtime.FuncForPC(value.Pointer()); f != nil {
+		return strings.TrimSuffix(f.Name(), "-fm")
+	}
+	return ""
+}
+
+func middlewareNames(middlewares []MiddleWareFunc) []string {
+	names := make([]string, 0, len(middlewares))
+	for _, middleware := range middlewares {
+		names = append(names, funcName(middleware))
+	}
+	return names
+}
@@ -0,0 +1,65 @@
+package plaud
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"plaudern/utils"
+	"strings"
+	"testing"
+)
+
+func authMiddleware(ctx *Context) *Error {
+	ctx.Next()
+	return nil
+}
+
+func loggingMiddleware(ctx *Context) *Error {
+	ctx.Next()
+	return nil
+}
+
+func listUsers(_ *Context) (*Data, *Error) {
+	return NewData("users"), nil
+}
+
+func TestServerRoutes(t *testing.T) {
+	server := New(":8000")
+	apiRouter := NewRouter("/api").Use(loggingMiddleware)
+	usersRouter := NewRouter("/users")
+	usersRouter.Get("/", listUsers).Use(authMiddleware)
+	apiRouter.Handle("/v1", usersRouter)
+
+	debugRouter := NewRouter("/debug")
+	debugRouter.Get("/routes", server.RoutesFunc())
+	server.Register(apiRouter, debugRouter)
+
+	routes := server.Routes()
+	utils.AssertEq(t, 2, len(routes))
+	utils.AssertEq(t, "GET", routes[0].Method)
+	utils.AssertEq(t, "/api/v1/users", routes[0].Pattern)
+	utils.AssertEq(t, "plaudern.listUsers", routes[0].Handler)
+	utils.AssertEq(t, "plaudern.loggingMiddleware,plaudern.authMiddleware", strings.Join(routes[0].Middlewares, ","))
+	utils.AssertEq(t, "/users", routes[0].Router)
+
+	req := httptest.NewRequest(http.MethodGet, "/debug/routes", http.NoBody)
+	res := httptest.NewRecorder()
+	server.server.ServeHTTP(res, req)
+	utils.AssertEq(t, http.StatusOK, res.Code)
+
+	var body struct {
+		Data []RouteInfo `json:"data"`
+	}
+	utils.AssertNoErr(t, json.NewDecoder(res.Body).Decode(&body))
+	utils.AssertEq(t, 2, len(body.Data))
+	utils.AssertEq(t, "/debug/routes", body.Data[1].Pattern)
+
+	req = httptest.NewRequest(http.MethodGet, "/debug/routes?format=table", http.NoBody)
+	res = httptest.NewRecorder()
+	server.server.ServeHTTP(res, req)
+	lines := strings.Split(strings.TrimSpace(res.Body.String()), "\n")
+	utils.AssertEq(t, 3, len(lines))
+	if !strings.HasPrefix(lines[0], "METHOD") || !strings.Contains(lines[1], "/api/v1/users") {
+		t.Fatalf("Invalid route table %s", res.Body.String())
+	}
+}
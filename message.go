@@ -5,7 +5,14 @@ import "net/http"
 type Data struct {
 	Data    interface{} `json:"data"`
 	Message string      `json:"message"`
-	code    int
+	// additional information next to the data, e.g. pagination
+	Meta map[string]any `json:"meta,omitempty"`
+	code int
+
+	header  http.Header
+	cookies []*http.Cookie
+	links   []Link
+	page    *Page
 }
 
 func NewData(message string) *Data {
@@ -27,3 +34,31 @@ func (e *Data) SetData(data interface{}) *Data {
 	e.Data = data
 	return e
 }
+
+func (e *Data) SetMeta(key string, value any) *Data {
+	if e.Meta == nil {
+		e.Meta = make(map[string]any)
+	}
+	e.Meta[key] = value
+	return e
+}
+
+// sets a response header, replacing the values set by the middlewares
+func (e *Data) SetHeader(key, value string) *Data {
+	if e.header == nil {
+		e.header = make(http.Header)
+	}
+	e.header.Set(key, value)
+	return e
+}
+
+func (e *Data) SetCookie(cookie *http.Cookie) *Data {
+	e.cookies = append(e.cookies, cookie)
+	return e
+}
+
+// links written to the Link header
+func (e *Data) SetLinks(links ...Link) *Data {
+	e.links = links
+	return e
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 )
 
@@ -191,3 +192,34 @@ func TestMiddlewareAbort(t *testing.T) {
 		t.Fatal("Unexpected execution order of middlewares and handler function")
 	}
 }
+
+func TestMiddlewareNestedRouterOrder(t *testing.T) {
+	var order []string
+	record := func(name string) MiddleWareFunc {
+		return func(ctx *Context) *Error {
+			order = append(order, name)
+			return nil
+		}
+	}
+
+	server := New(":8000")
+	parentRouter := NewRouter("/")
+	parentRouter.Use(record("parent"))
+	childRouter := NewRouter("/")
+	childRouter.Use(record("child"))
+	childRouter.Get("/", func(ctx *Context) (*Data, *Error) {
+		order = append(order, "handler")
+		return nil, nil
+	}).Use(record("route"))
+	parentRouter.Handle("/child", childRouter)
+	server.Register(parentRouter)
+
+	req := httptest.NewRequest(http.MethodGet, "/child", nil)
+	res := httptest.NewRecorder()
+	server.server.ServeHTTP(res, req)
+
+	// every middleware runs once, outermost router first
+	if got := strings.Join(order, ","); got != "parent,child,route,handler" {
+		t.Fatalf("Expected: parent,child,route,handler Got: %s", got)
+	}
+}
@@ -5,3 +5,18 @@ package plaud
 // the middleware registered in the router takes precedence over the middleware registered in the routes
 // if a error is returned the middleware chain is terminated , else the next middleware or the function is automatically called
 type MiddleWareFunc func(c *Context) *Error
+
+// executes the middlewares followed by the handler
+// the errors collected on the way are passed to the route's ErrorHandler
+func runChain(ctx *Context, middlewares []MiddleWareFunc, handler MiddleWareFunc) {
+	handlers := make([]MiddleWareFunc, len(middlewares)+1)
+	copy(handlers, middlewares)
+	handlers[len(middlewares)] = handler
+	defer ctx.done()
+
+	ctx.ResponseWriter = &responseWriter{ResponseWriter: ctx.ResponseWriter}
+	ctx.SetMiddlewares(handlers)
+	// handling middlewares
+	ctx.Next()
+	ctx.handleErrors()
+}
@@ -0,0 +1,227 @@
+package plaud
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"iter"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+)
+
+// memory used by MultipartForm before spilling files to disk
+const DefaultMaxMemory = 32 << 20
+
+// bytes read from every part to sniff its content type
+const sniffLength = 512
+
+// parses a multipart/form-data body
+// files larger than maxMemory are stored in temporary files removed after the request
+func (c *Context) MultipartForm(maxMemory int64) (*multipart.Form, *Error) {
+	if c.Request.MultipartForm == nil {
+		if err := c.Request.ParseMultipartForm(maxMemory); err != nil {
+			return nil, NewError("Invalid multipart form").SetCode(http.StatusBadRequest)
+		}
+		form := c.Request.MultipartForm
+		c.OnDone(func() {
+			_ = form.RemoveAll()
+		})
+	}
+	return c.Request.MultipartForm, nil
+}
+
+// returns the first file of the form field
+func (c *Context) FormFile(name string) (multipart.File, *multipart.FileHeader, *Error) {
+	form, err := c.MultipartForm(DefaultMaxMemory)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	headers := form.File[name]
+	if len(headers) == 0 {
+		return nil, nil, NewError("Missing file " + name).SetCode(http.StatusBadRequest)
+	}
+	file, openErr := headers[0].Open()
+	if openErr != nil {
+		return nil, nil, NewError("Failed to read file " + name).SetCode(http.StatusInternalServerError)
+	}
+	c.OnDone(func() {
+		file.Close()
+	})
+	return file, headers[0], nil
+}
+
+type UploadOptions struct {
+	// largest accepted file in bytes, 0 means unlimited
+	MaxFileSize int64
+	// largest accepted body in bytes, 0 means unlimited
+	MaxTotalSize int64
+	// largest total size of the form fields in bytes, defaults to DefaultMaxMemory
+	// applies even when MaxTotalSize is 0
+	MaxFieldsSize int64
+	// content types sniffed from the file content, "image/*" matches every image
+	// empty allows every type
+	AllowedTypes []string
+	// directory of the temporary files, defaults to os.TempDir
+	Dir string
+	// receives the content instead of a temporary file when set
+	Sink func(*Upload) (io.WriteCloser, error)
+}
+
+// a file streamed from a multipart body
+type Upload struct {
+	FieldName string
+	FileName  string
+	// sniffed from the content, the header sent by the client is ignored
+	ContentType string
+	Size        int64
+	// temporary file holding the content, empty when a Sink is used
+	// removed after the request unless moved away
+	Path string
+}
+
+// streams the files of a multipart/form-data body one at a time, without buffering them in memory
+// form fields are added to Request.PostForm as they are read
+// fields larger than MaxFieldsSize together answer 413
+// iteration stops after the first error
+func (c *Context) Uploads(opts UploadOptions) iter.Seq2[*Upload, *Error] {
+	return func(yield func(*Upload, *Error) bool) {
+		if opts.MaxTotalSize > 0 {
+			c.Request.Body = http.MaxBytesReader(c.ResponseWriter, c.Request.Body, opts.MaxTotalSize)
+		}
+		reader, err := c.Request.MultipartReader()
+		if err != nil {
+			yield(nil, NewError("Invalid multipart form").SetCode(http.StatusBadRequest))
+			return
+		}
+
+		if c.Request.PostForm == nil {
+			c.Request.PostForm = make(url.Values)
+		}
+		fieldsLeft := opts.MaxFieldsSize
+		if fieldsLeft <= 0 {
+			fieldsLeft = DefaultMaxMemory
+		}
+
+		for {
+			part, err := reader.NextPart()
+			if errors.Is(err, io.EOF) {
+				return
+			}
+			if err != nil {
+				yield(nil, uploadError(err))
+				return
+			}
+
+			if part.FileName() == "" {
+				value, err := io.ReadAll(io.LimitReader(part, fieldsLeft+1))
+				part.Close()
+				if err != nil {
+					yield(nil, uploadError(err))
+					return
+				}
+				// a cut value would pass as complete
+				if int64(len(value)) > fieldsLeft {
+					yield(nil, NewError("Form fields too large").SetCode(http.StatusRequestEntityTooLarge))
+					return
+				}
+				fieldsLeft -= int64(len(value))
+				c.Request.PostForm.Add(part.FormName(), string(value))
+				continue
+			}
+
+			upload, uploadErr := c.receive(part, opts)
+			part.Close()
+			if uploadErr != nil {
+				yield(nil, uploadErr)
+				return
+			}
+			if !yield(upload, nil) {
+				return
+			}
+		}
+	}
+}
+
+func (c *Context) receive(part *multipart.Part, opts UploadOptions) (*Upload, *Error) {
+	sniffed := make([]byte, sniffLength)
+	n, err := io.ReadFull(part, sniffed)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return nil, uploadError(err)
+	}
+	sniffed = sniffed[:n]
+
+	upload := &Upload{
+		FieldName:   part.FormName(),
+		FileName:    path.Base(strings.ReplaceAll(part.FileName(), `\`, "/")),
+		ContentType: http.DetectContentType(sniffed),
+	}
+	if !allowedType(upload.ContentType, opts.AllowedTypes) {
+		return nil, NewError("File type not allowed: " + upload.ContentType).SetCode(http.StatusUnsupportedMediaType)
+	}
+
+	var sink io.WriteCloser
+	if opts.Sink != nil {
+		sink, err = opts.Sink(upload)
+	} else {
+		var file *os.File
+		file, err = os.CreateTemp(opts.Dir, "plaud-upload-*")
+		if file != nil {
+			upload.Path = file.Name()
+			c.OnDone(func() {
+				_ = os.Remove(file.Name())
+			})
+		}
+		sink = file
+	}
+	if err != nil {
+		return nil, NewError("Failed to store file").SetCode(http.StatusInternalServerError)
+	}
+
+	content := io.MultiReader(bytes.NewReader(sniffed), part)
+	if opts.MaxFileSize > 0 {
+		content = io.LimitReader(content, opts.MaxFileSize+1)
+	}
+
+	upload.Size, err = io.Copy(sink, content)
+	if closeErr := sink.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, uploadError(err)
+	}
+	if opts.MaxFileSize > 0 && upload.Size > opts.MaxFileSize {
+		return nil, NewError("File too large").SetCode(http.StatusRequestEntityTooLarge)
+	}
+
+	return upload, nil
+}
+
+func allowedType(contentType string, allowed []string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	for _, pattern := range allowed {
+		if prefix, ok := strings.CutSuffix(pattern, "/*"); ok {
+			if strings.HasPrefix(mediaType, prefix+"/") {
+				return true
+			}
+		} else if mediaType == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+func uploadError(err error) *Error {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return NewError("Request body too large").SetCode(http.StatusRequestEntityTooLarge)
+	}
+	return NewError("Invalid multipart form").SetCode(http.StatusBadRequest)
+}
@@ -0,0 +1,130 @@
+package plaud
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"plaudern/utils"
+	"strings"
+	"testing"
+)
+
+var pngHeader = []byte("\x89PNG\r\n\x1a\nrest-of-the-image")
+
+func multipartBody(t *testing.T, files map[string][]byte) (*bytes.Buffer, string) {
+	t.Helper()
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	utils.AssertNoErr(t, writer.WriteField("title", "holiday"))
+	for name, content := range files {
+		part, err := writer.CreateFormFile("files", name)
+		utils.AssertNoErr(t, err)
+		_, err = part.Write(content)
+		utils.AssertNoErr(t, err)
+	}
+	utils.AssertNoErr(t, writer.Close())
+	return body, writer.FormDataContentType()
+}
+
+func TestUploads(t *testing.T) {
+	var tempFiles []string
+
+	server := New(":8000")
+	testRouter := NewRouter("/")
+	testRouter.Post("/upload", func(ctx *Context) (*Data, *Error) {
+		var names []string
+		for upload, err := range ctx.Uploads(UploadOptions{MaxFileSize: 64, AllowedTypes: []string{"image/*"}}) {
+			if err != nil {
+				return nil, err
+			}
+			content, readErr := os.ReadFile(upload.Path)
+			utils.AssertNoErr(t, readErr)
+			utils.AssertEq(t, string(pngHeader), string(content))
+			utils.AssertEq(t, "image/png", upload.ContentType)
+			tempFiles = append(tempFiles, upload.Path)
+			names = append(names, upload.FileName)
+		}
+		utils.AssertEq(t, "holiday", ctx.Request.PostForm.Get("title"))
+		return NewData("uploaded").SetData(names), nil
+	})
+	testRouter.Post("/form", func(ctx *Context) (*Data, *Error) {
+		file, header, err := ctx.FormFile("files")
+		if err != nil {
+			return nil, err
+		}
+		content, readErr := io.ReadAll(file)
+		utils.AssertNoErr(t, readErr)
+		return NewData(header.Filename).SetData(len(content)), nil
+	})
+	server.Register(testRouter)
+
+	tests := []struct {
+		path  string
+		files map[string][]byte
+		code  int
+	}{
+		{"/upload", map[string][]byte{"cat.png": pngHeader}, http.StatusOK},
+		{"/upload", map[string][]byte{"fake.png": []byte("#!/bin/sh\nrm -rf /")}, http.StatusUnsupportedMediaType},
+		{"/upload", map[string][]byte{"big.png": append(pngHeader, bytes.Repeat([]byte("x"), 64)...)}, http.StatusRequestEntityTooLarge},
+		{"/form", map[string][]byte{"cat.png": pngHeader}, http.StatusOK},
+	}
+
+	for _, test := range tests {
+		body, contentType := multipartBody(t, test.files)
+		req := httptest.NewRequest(http.MethodPost, test.path, body)
+		req.Header.Set("Content-Type", contentType)
+		res := httptest.NewRecorder()
+		server.server.ServeHTTP(res, req)
+
+		if res.Code != test.code {
+			t.Fatalf("%s Expected: %v Got: %v %s", test.path, test.code, res.Code, res.Body.String())
+		}
+	}
+
+	utils.AssertEq(t, 1, len(tempFiles))
+	if _, err := os.Stat(tempFiles[0]); !os.IsNotExist(err) {
+		t.Fatal("Temporary upload was not removed after the request")
+	}
+}
+
+func TestUploadsFieldsLimit(t *testing.T) {
+	server := New(":8000")
+	testRouter := NewRouter("/")
+	testRouter.Post("/upload", func(ctx *Context) (*Data, *Error) {
+		for _, err := range ctx.Uploads(UploadOptions{MaxFieldsSize: 16}) {
+			if err != nil {
+				return nil, err
+			}
+		}
+		return NewData("uploaded"), nil
+	})
+	server.Register(testRouter)
+
+	tests := []struct {
+		fields []string
+		code   int
+	}{
+		{[]string{"short"}, http.StatusOK},
+		{[]string{strings.Repeat("x", 17)}, http.StatusRequestEntityTooLarge},
+		// the limit is shared by all the fields
+		{[]string{strings.Repeat("x", 10), strings.Repeat("y", 10)}, http.StatusRequestEntityTooLarge},
+	}
+
+	for _, test := range tests {
+		body := &bytes.Buffer{}
+		writer := multipart.NewWriter(body)
+		for _, value := range test.fields {
+			utils.AssertNoErr(t, writer.WriteField("note", value))
+		}
+		utils.AssertNoErr(t, writer.Close())
+
+		req := httptest.NewRequest(http.MethodPost, "/upload", body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		res := httptest.NewRecorder()
+		server.server.ServeHTTP(res, req)
+		utils.AssertEq(t, test.code, res.Code)
+	}
+}
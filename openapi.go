@@ -0,0 +1,569 @@
+package plaud
+
+import (
+	_ "embed"
+	"encoding"
+	"fmt"
+	"html/template"
+	"net/http"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const OpenAPIVersion = "3.1.0"
+
+// documentation of a route
+type RouteDoc struct {
+	Summary     string
+	Description string
+	Tags        []string
+	// values or reflect.Types of the json request body and response data
+	Request  any
+	Response any
+	// struct describing the query parameters, fields use the `query` tag
+	Query any
+	// status of the successful response, defaults to 200
+	Status int
+	// left out of the document
+	Hidden bool
+}
+
+// fills the request, response, query and status left unset from prev
+// describing a typed route keeps the types recorded by its helper
+func (doc RouteDoc) merge(prev RouteDoc) RouteDoc {
+	if doc.Request == nil {
+		doc.Request = prev.Request
+	}
+	if doc.Response == nil {
+		doc.Response = prev.Response
+	}
+	if doc.Query == nil {
+		doc.Query = prev.Query
+	}
+	if doc.Status == 0 {
+		doc.Status = prev.Status
+	}
+	return doc
+}
+
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+	Name         string `json:"name,omitempty"`
+	In           string `json:"in,omitempty"`
+	Description  string `json:"description,omitempty"`
+}
+
+type OpenAPIConfig struct {
+	Title       string
+	Version     string
+	Description string
+	Servers     []string
+	// schema of the responses of routes using a custom Envelope
+	// receives the schema of the data, the body is left undescribed when nil
+	EnvelopeSchema func(data Schema) Schema
+
+	securitySchemes map[string]SecurityScheme
+	// middleware func name -> scheme name
+	securityMiddlewares map[string]string
+}
+
+// routes running the middleware are documented as requiring the security scheme
+//
+//	cfg.Secure(AuthMiddleware, "bearer", SecurityScheme{Type: "http", Scheme: "bearer"})
+func (cfg *OpenAPIConfig) Secure(middleware MiddleWareFunc, name string, scheme SecurityScheme) {
+	if cfg.securitySchemes == nil {
+		cfg.securitySchemes = make(map[string]SecurityScheme)
+		cfg.securityMiddlewares = make(map[string]string)
+	}
+	cfg.securitySchemes[name] = scheme
+	cfg.securityMiddlewares[funcName(middleware)] = name
+}
+
+type OpenAPIDocument struct {
+	OpenAPI    string                          `json:"openapi"`
+	Info       OpenAPIInfo                     `json:"info"`
+	Servers    []map[string]string             `json:"servers,omitempty"`
+	Paths      map[string]map[string]Operation `json:"paths"`
+	Components OpenAPIComponents               `json:"components"`
+}
+
+type OpenAPIInfo struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+type OpenAPIComponents struct {
+	Schemas         map[string]Schema         `json:"schemas"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+type Operation struct {
+	OperationID string                `json:"operationId,omitempty"`
+	Summary     string                `json:"summary,omitempty"`
+	Description string                `json:"description,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]Response   `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+type Parameter struct {
+	Name     string `json:"name"`
+	In       string `json:"in"`
+	Required bool   `json:"required,omitempty"`
+	Schema   Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema Schema `json:"schema"`
+}
+
+// json schema
+type Schema map[string]any
+
+// builds the OpenAPI document of every route registered with the server
+func (s *Server) OpenAPI(cfg OpenAPIConfig) *OpenAPIDocument {
+	doc := &OpenAPIDocument{
+		OpenAPI: OpenAPIVersion,
+		Info:    OpenAPIInfo{Title: cfg.Title, Version: cfg.Version, Description: cfg.Description},
+		Paths:   make(map[string]map[string]Operation),
+		Components: OpenAPIComponents{
+			Schemas: map[string]Schema{
+				"Error": {
+					"type": "object",
+					"properties": map[string]any{
+						"data":    Schema{},
+						"message": Schema{"type": "string"},
+						"code":    Schema{"type": "string"},
+					},
+					"required": []string{"message"},
+				},
+			},
+			SecuritySchemes: cfg.securitySchemes,
+		},
+	}
+	for _, server := range cfg.Servers {
+		doc.Servers = append(doc.Servers, map[string]string{"url": server})
+	}
+
+	schemas := &schemaBuilder{components: doc.Components.Schemas, names: make(map[reflect.Type]string)}
+	operationIDs := make(map[string]bool)
+	for _, route := range s.Routes() {
+		if route.Method == "" || route.Doc.Hidden {
+			continue
+		}
+
+		path, params := openAPIPath(route.Pattern)
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = make(map[string]Operation)
+		}
+		op := buildOperation(route, params, schemas, cfg)
+		op.OperationID = uniqueOperationID(op.OperationID, operationIDs)
+		doc.Paths[path][strings.ToLower(route.Method)] = op
+	}
+
+	return doc
+}
+
+func buildOperation(route RouteInfo, params []string, schemas *schemaBuilder, cfg OpenAPIConfig) Operation {
+	op := Operation{
+		OperationID: route.Name,
+		Summary:     route.Doc.Summary,
+		Description: route.Doc.Description,
+		Tags:        route.Doc.Tags,
+		Responses:   make(map[string]Response),
+	}
+	if op.OperationID == "" {
+		op.OperationID = operationID(route.Handler)
+	}
+
+	for _, param := range params {
+		op.Parameters = append(op.Parameters, Parameter{Name: param, In: "path", Required: true, Schema: Schema{"type": "string"}})
+	}
+	if queryType := docType(route.Doc.Query); queryType != nil {
+		op.Parameters = append(op.Parameters, queryParameters(queryType, schemas)...)
+	}
+
+	if requestType := docType(route.Doc.Request); requestType != nil {
+		op.RequestBody = &RequestBody{
+			Required: true,
+			Content:  map[string]MediaType{"application/json": {Schema: schemas.schema(requestType)}},
+		}
+	}
+
+	data := Schema{}
+	if responseType := docType(route.Doc.Response); responseType != nil {
+		data = schemas.schema(responseType)
+	}
+	status := route.Doc.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	op.Responses[strconv.Itoa(status)] = Response{
+		Description: http.StatusText(status),
+		Content:     map[string]MediaType{"application/json": {Schema: envelopeSchema(route.config.envelope, data, cfg)}},
+	}
+
+	if route.config.format == ProblemDetails {
+		schemas.components["Problem"] = problemSchema
+		op.Responses["default"] = Response{
+			Description: "Error",
+			Content:     map[string]MediaType{problemContentType: {Schema: Schema{"$ref": "#/components/schemas/Problem"}}},
+		}
+	} else {
+		op.Responses["default"] = Response{
+			Description: "Error",
+			Content:     map[string]MediaType{"application/json": {Schema: Schema{"$ref": "#/components/schemas/Error"}}},
+		}
+	}
+
+	for _, middleware := range route.Middlewares {
+		if scheme, ok := cfg.securityMiddlewares[middleware]; ok {
+			op.Security = append(op.Security, map[string][]string{scheme: {}})
+		}
+	}
+
+	return op
+}
+
+// converts a mux pattern to an OpenAPI path and its parameters
+// /files/{path...} -> /files/{path}, /{$} -> /
+func openAPIPath(pattern string) (string, []string) {
+	var params []string
+	segments := strings.Split(pattern, "/")
+	for i, segment := range segments {
+		if !strings.HasPrefix(segment, "{") || !strings.HasSuffix(segment, "}") {
+			continue
+		}
+		name := strings.TrimSuffix(strings.TrimSuffix(strings.TrimPrefix(segment, "{"), "}"), "...")
+		if name == "$" {
+			segments[i] = ""
+			continue
+		}
+		segments[i] = "{" + name + "}"
+		params = append(params, name)
+	}
+	return strings.Join(segments, "/"), params
+}
+
+// schema of the body the envelope builds around the data
+func envelopeSchema(envelope Envelope, data Schema, cfg OpenAPIConfig) Schema {
+	switch {
+	case envelope == nil || sameFunc(envelope, DefaultEnvelope):
+		return Schema{
+			"type": "object",
+			"properties": map[string]any{
+				"data":    data,
+				"message": Schema{"type": "string"},
+				"meta":    Schema{"type": "object"},
+			},
+		}
+	case sameFunc(envelope, RawEnvelope):
+		return data
+	case cfg.EnvelopeSchema != nil:
+		return cfg.EnvelopeSchema(data)
+	default:
+		return Schema{}
+	}
+}
+
+func sameFunc(a, b Envelope) bool {
+	return reflect.ValueOf(a).Pointer() == reflect.ValueOf(b).Pointer()
+}
+
+var problemSchema = Schema{
+	"type": "object",
+	"properties": map[string]any{
+		"type":     Schema{"type": "string", "format": "uri-reference"},
+		"title":    Schema{"type": "string"},
+		"status":   Schema{"type": "integer"},
+		"detail":   Schema{"type": "string"},
+		"instance": Schema{"type": "string", "format": "uri-reference"},
+	},
+	"required": []string{"status", "title", "type"},
+}
+
+// plaudern.(*UserAPI).GetUsers -> GetUsers
+func operationID(handler string) string {
+	if i := strings.LastIndex(handler, "."); i >= 0 {
+		handler = handler[i+1:]
+	}
+	if strings.HasPrefix(handler, "func") {
+		return ""
+	}
+	return handler
+}
+
+// operationIds must be unique, routes sharing a handler get a numbered suffix
+func uniqueOperationID(id string, used map[string]bool) string {
+	if id == "" {
+		return ""
+	}
+	unique := id
+	for i := 2; used[unique]; i++ {
+		unique = fmt.Sprintf("%s_%d", id, i)
+	}
+	used[unique] = true
+	return unique
+}
+
+func docType(value any) reflect.Type {
+	if value == nil {
+		return nil
+	}
+	if t, ok := value.(reflect.Type); ok {
+		return t
+	}
+	return reflect.TypeOf(value)
+}
+
+func queryParameters(t reflect.Type, schemas *schemaBuilder) []Parameter {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var params []Parameter
+	for _, field := range reflect.VisibleFields(t) {
+		name := strings.Split(field.Tag.Get("query"), ",")[0]
+		if !field.IsExported() || name == "" || name == "-" {
+			continue
+		}
+		params = append(params, Parameter{Name: name, In: "query", Schema: schemas.schema(field.Type)})
+	}
+	return params
+}
+
+// builds json schemas by reflection, named structs become components
+type schemaBuilder struct {
+	components map[string]Schema
+	// component key of every named struct
+	names map[reflect.Type]string
+}
+
+// package qualified key of a named type, unique within the document
+// example.com/app/models.User -> example.com_app_models.User
+func (b *schemaBuilder) componentName(t reflect.Type) string {
+	if name, ok := b.names[t]; ok {
+		return name
+	}
+
+	// keys may only contain letters, digits, '.', '-' and '_'
+	sanitize := func(r rune) rune {
+		if r == '.' || r == '-' || r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}
+	base := strings.Map(sanitize, t.PkgPath()+"."+t.Name())
+
+	name := base
+	for i := 2; ; i++ {
+		if _, taken := b.components[name]; !taken {
+			break
+		}
+		name = fmt.Sprintf("%s_%d", base, i)
+	}
+	b.names[t] = name
+	return name
+}
+
+var (
+	timeType          = reflect.TypeFor[time.Time]()
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+)
+
+// implemented by the type or its pointer, as encoding/json checks
+func implements(t, iface reflect.Type) bool {
+	return t.Implements(iface) || reflect.PointerTo(t).Implements(iface)
+}
+
+func (b *schemaBuilder) schema(t reflect.Type) Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return Schema{"type": "string", "format": "date-time"}
+	}
+	// the json form of these types isn't their go structure, e.g. UUIDs
+	if implements(t, jsonMarshalerType) {
+		return Schema{}
+	}
+	if implements(t, textMarshalerType) {
+		return Schema{"type": "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return Schema{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return Schema{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return Schema{"type": "number"}
+	case reflect.String:
+		return Schema{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		// only byte slices are base64, byte arrays are arrays of numbers
+		if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+			return Schema{"type": "string", "contentEncoding": "base64"}
+		}
+		return Schema{"type": "array", "items": b.schema(t.Elem())}
+	case reflect.Map:
+		return Schema{"type": "object", "additionalProperties": b.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return b.object(t)
+		}
+		name, known := b.names[t]
+		if !known {
+			name = b.componentName(t)
+			// placeholder so recursive types terminate
+			b.components[name] = Schema{}
+			b.components[name] = b.object(t)
+		}
+		return Schema{"$ref": "#/components/schemas/" + name}
+	default:
+		return Schema{}
+	}
+}
+
+type jsonField struct {
+	name    string
+	options string
+	reflect.StructField
+}
+
+// fields of the struct as encoding/json sees them
+// embedded structs without a json name are flattened, shallower fields shadow deeper ones
+func jsonFields(t reflect.Type) []jsonField {
+	type embedded struct {
+		t     reflect.Type
+		index []int
+	}
+
+	var fields []jsonField
+	seen := make(map[string]bool)
+	visited := make(map[reflect.Type]bool)
+
+	// breadth first, one level of embedding at a time
+	for level := []embedded{{t: t}}; len(level) > 0; {
+		var next []embedded
+		for _, current := range level {
+			if visited[current.t] {
+				continue
+			}
+			visited[current.t] = true
+
+			for i := range current.t.NumField() {
+				field := current.t.Field(i)
+				field.Index = append(slices.Clone(current.index), i)
+
+				tag := field.Tag.Get("json")
+				if tag == "-" {
+					continue
+				}
+				name, options, _ := strings.Cut(tag, ",")
+
+				// embedded structs count even when their type is unexported
+				embeddedStruct := false
+				if field.Anonymous {
+					fieldType := field.Type
+					if fieldType.Kind() == reflect.Pointer {
+						fieldType = fieldType.Elem()
+					}
+					embeddedStruct = fieldType.Kind() == reflect.Struct
+					if name == "" && embeddedStruct {
+						next = append(next, embedded{t: fieldType, index: field.Index})
+						continue
+					}
+				}
+				if !field.IsExported() && !embeddedStruct {
+					continue
+				}
+
+				if name == "" {
+					name = field.Name
+				}
+				if seen[name] {
+					continue
+				}
+				seen[name] = true
+				fields = append(fields, jsonField{name: name, options: options, StructField: field})
+			}
+		}
+		level = next
+	}
+	return fields
+}
+
+func (b *schemaBuilder) object(t reflect.Type) Schema {
+	properties := make(map[string]any)
+	var required []string
+
+	for _, field := range jsonFields(t) {
+		properties[field.name] = b.schema(field.Type)
+		if !strings.Contains(field.options, "omitempty") && !strings.Contains(field.options, "omitzero") && field.Type.Kind() != reflect.Pointer {
+			required = append(required, field.name)
+		}
+	}
+
+	schema := Schema{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		sort.Strings(required)
+		schema["required"] = required
+	}
+	return schema
+}
+
+// self contained page rendering the document, no third party scripts
+//
+//go:embed openapi_docs.html
+var docsHTML string
+
+var docsPage = template.Must(template.New("docs").Parse(docsHTML))
+
+// serves the OpenAPI document at path and a docs page at path/docs
+// the document is built on every request from the routes registered with the server
+func (s *Server) ServeOpenAPI(router HTTPRouter, path string, cfg OpenAPIConfig) {
+	path = strings.TrimRight(path, "/")
+
+	if route := router.Get(path, func(ctx *Context) (*Data, *Error) {
+		ctx.JSON(http.StatusOK, s.OpenAPI(cfg))
+		return nil, nil
+	}); route != nil {
+		route.Describe(RouteDoc{Hidden: true})
+	}
+
+	if route := router.Get(path+"/docs", func(ctx *Context) (*Data, *Error) {
+		specURL := strings.TrimSuffix(ctx.Request.URL.Path, "/docs")
+		ctx.Header("Content-Type", "text/html; charset=utf-8")
+		ctx.Status(http.StatusOK)
+		if err := docsPage.Execute(ctx.ResponseWriter, map[string]string{"Title": cfg.Title, "SpecURL": specURL}); err != nil {
+			return nil, NewError(fmt.Sprintf("Failed to render docs: %v", err)).SetCode(http.StatusInternalServerError)
+		}
+		return nil, nil
+	}); route != nil {
+		route.Describe(RouteDoc{Hidden: true})
+	}
+}
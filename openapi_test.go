@@ -0,0 +1,219 @@
+package plaud
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"plaudern/utils"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+type openAPIUser struct {
+	ID        int            `json:"id"`
+	Name      string         `json:"name"`
+	Email     string         `json:"email,omitempty"`
+	CreatedAt time.Time      `json:"created_at"`
+	Friends   []*openAPIUser `json:"friends,omitempty"`
+	password  string
+}
+
+type openAPIUserQuery struct {
+	Limit int `query:"limit"`
+}
+
+func TestOpenAPI(t *testing.T) {
+	server := New(":8000")
+	apiRouter := NewRouter("/api").Use(authMiddleware)
+	apiRouter.Get("/users/{id}", listUsers).Name("user.show").Describe(RouteDoc{
+		Summary:  "Get a user",
+		Tags:     []string{"users"},
+		Response: openAPIUser{},
+		Query:    openAPIUserQuery{},
+	})
+	apiRouter.Post("/users", listUsers).Describe(RouteDoc{Request: openAPIUser{}, Response: openAPIUser{}, Status: http.StatusCreated})
+	apiRouter.Put("/users/{id}", listUsers)
+
+	cfg := OpenAPIConfig{Title: "Users", Version: "1.0.0"}
+	cfg.Secure(authMiddleware, "bearer", SecurityScheme{Type: "http", Scheme: "bearer"})
+
+	docsRouter := NewRouter("/")
+	server.ServeOpenAPI(docsRouter, "/openapi", cfg)
+	server.Register(apiRouter, docsRouter)
+
+	req := httptest.NewRequest(http.MethodGet, "/openapi", http.NoBody)
+	res := httptest.NewRecorder()
+	server.server.ServeHTTP(res, req)
+	utils.AssertEq(t, http.StatusOK, res.Code)
+
+	var doc map[string]any
+	utils.AssertNoErr(t, json.NewDecoder(res.Body).Decode(&doc))
+	utils.AssertEq(t, OpenAPIVersion, doc["openapi"].(string))
+
+	paths := doc["paths"].(map[string]any)
+	utils.AssertEq(t, 2, len(paths))
+
+	get := paths["/api/users/{id}"].(map[string]any)["get"].(map[string]any)
+	utils.AssertEq(t, "user.show", get["operationId"].(string))
+	params := get["parameters"].([]any)
+	utils.AssertEq(t, 2, len(params))
+	utils.AssertEq(t, "id", params[0].(map[string]any)["name"].(string))
+	utils.AssertEq(t, "limit", params[1].(map[string]any)["name"].(string))
+	security := get["security"].([]any)
+	utils.AssertEq(t, 1, len(security))
+
+	post := paths["/api/users"].(map[string]any)["post"].(map[string]any)
+	utils.AssertEq(t, "listUsers", post["operationId"].(string))
+	// the handler's name is taken
+	put := paths["/api/users/{id}"].(map[string]any)["put"].(map[string]any)
+	utils.AssertEq(t, "listUsers_2", put["operationId"].(string))
+	if _, ok := post["responses"].(map[string]any)["201"]; !ok {
+		t.Fatal("Expected a 201 response")
+	}
+
+	schemas := doc["components"].(map[string]any)["schemas"].(map[string]any)
+	user := schemas["plaudern.openAPIUser"].(map[string]any)
+	properties := user["properties"].(map[string]any)
+	utils.AssertEq(t, 5, len(properties))
+	utils.AssertEq(t, "date-time", properties["created_at"].(map[string]any)["format"].(string))
+	friends := properties["friends"].(map[string]any)["items"].(map[string]any)
+	utils.AssertEq(t, "#/components/schemas/plaudern.openAPIUser", friends["$ref"].(string))
+	required, _ := json.Marshal(user["required"])
+	utils.AssertEq(t, `["created_at","id","name"]`, string(required))
+
+	req = httptest.NewRequest(http.MethodGet, "/openapi/docs", http.NoBody)
+	res = httptest.NewRecorder()
+	server.server.ServeHTTP(res, req)
+	utils.AssertEq(t, http.StatusOK, res.Code)
+	if !strings.Contains(res.Body.String(), `const specURL = "/openapi"`) {
+		t.Fatalf("Docs page does not point at the document %s", res.Body.String())
+	}
+	// served without third party resources
+	utils.AssertEq(t, false, strings.Contains(res.Body.String(), "https://"))
+}
+
+// same name as net/http.Cookie
+type Cookie struct {
+	Flavor string `json:"flavor"`
+}
+
+type openAPIPage[T any] struct {
+	Items []T `json:"items"`
+}
+
+type openAPICookies struct {
+	Local  Cookie                        `json:"local"`
+	Stdlib http.Cookie                   `json:"stdlib"`
+	Page   openAPIPage[openAPIUserQuery] `json:"page"`
+}
+
+func TestOpenAPIComponentNames(t *testing.T) {
+	server := New(":8000")
+	testRouter := NewRouter("/")
+	testRouter.Get("/cookies", listUsers).Describe(RouteDoc{Response: openAPICookies{}})
+	server.Register(testRouter)
+
+	schemas := server.OpenAPI(OpenAPIConfig{}).Components.Schemas
+	valid := regexp.MustCompile(`^[a-zA-Z0-9.\-_]+$`)
+	for name := range schemas {
+		if !valid.MatchString(name) {
+			t.Fatalf("Invalid component key %s", name)
+		}
+	}
+
+	properties := schemas["plaudern.openAPICookies"]["properties"].(map[string]any)
+	utils.AssertEq[any](t, "#/components/schemas/plaudern.Cookie", properties["local"].(Schema)["$ref"])
+	utils.AssertEq[any](t, "#/components/schemas/net_http.Cookie", properties["stdlib"].(Schema)["$ref"])
+	utils.AssertEq[any](t, "#/components/schemas/plaudern.openAPIPage_plaudern.openAPIUserQuery_", properties["page"].(Schema)["$ref"])
+}
+
+func TestOpenAPIEnvelopes(t *testing.T) {
+	server := New(":8000").SetErrorFormat(ProblemDetails)
+	testRouter := NewRouter("/")
+	testRouter.Get("/wrapped", listUsers).Describe(RouteDoc{Response: openAPIUser{}})
+
+	rawRouter := NewRouter("/raw")
+	rawRouter.SetEnvelope(RawEnvelope).SetErrorFormat(EnvelopeErrors)
+	rawRouter.Get("/", listUsers).Describe(RouteDoc{Response: openAPIUser{}})
+
+	customRouter := NewRouter("/custom")
+	customRouter.SetEnvelope(func(ctx *Context, data *Data) any {
+		return map[string]any{"result": data.Data}
+	})
+	customRouter.Get("/", listUsers).Describe(RouteDoc{Response: openAPIUser{}})
+	server.Register(testRouter, rawRouter, customRouter)
+
+	doc := server.OpenAPI(OpenAPIConfig{
+		EnvelopeSchema: func(data Schema) Schema {
+			return Schema{"type": "object", "properties": map[string]any{"result": data}}
+		},
+	})
+	success := func(path string) Schema {
+		return doc.Paths[path]["get"].Responses["200"].Content["application/json"].Schema
+	}
+	userRef := "#/components/schemas/plaudern.openAPIUser"
+
+	wrapped := success("/wrapped")["properties"].(map[string]any)
+	utils.AssertEq[any](t, userRef, wrapped["data"].(Schema)["$ref"])
+	utils.AssertEq[any](t, userRef, success("/raw")["$ref"])
+	custom := success("/custom")["properties"].(map[string]any)
+	utils.AssertEq[any](t, userRef, custom["result"].(Schema)["$ref"])
+
+	problem := doc.Paths["/wrapped"]["get"].Responses["default"].Content["application/problem+json"]
+	utils.AssertEq[any](t, "#/components/schemas/Problem", problem.Schema["$ref"])
+	envelope := doc.Paths["/raw"]["get"].Responses["default"].Content["application/json"]
+	utils.AssertEq[any](t, "#/components/schemas/Error", envelope.Schema["$ref"])
+}
+
+func TestOpenAPIEmbeddedStructs(t *testing.T) {
+	server := New(":8000")
+	testRouter := NewRouter("/")
+	testRouter.Get("/document", listUsers).Describe(RouteDoc{Response: fieldDocument{}})
+	server.Register(testRouter)
+
+	schemas := server.OpenAPI(OpenAPIConfig{}).Components.Schemas
+	properties := schemas["plaudern.fieldDocument"]["properties"].(map[string]any)
+	utils.AssertEq(t, 3, len(properties))
+	utils.AssertEq[any](t, "#/components/schemas/plaudern.fieldMeta", properties["meta"].(Schema)["$ref"])
+	if _, ok := properties["created"]; !ok {
+		t.Fatal("Expected the untagged embedded struct to be flattened")
+	}
+}
+
+type openAPIID [16]byte
+
+func (id openAPIID) MarshalText() ([]byte, error) {
+	return []byte(fmt.Sprintf("%x", id[:])), nil
+}
+
+type openAPIRaw struct {
+	Value string
+}
+
+func (r *openAPIRaw) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.Value)
+}
+
+type openAPIBytes struct {
+	Hash  [4]byte    `json:"hash"`
+	Raw   []byte     `json:"raw"`
+	ID    openAPIID  `json:"id"`
+	Extra openAPIRaw `json:"extra"`
+}
+
+func TestOpenAPIJSONForms(t *testing.T) {
+	server := New(":8000")
+	testRouter := NewRouter("/")
+	testRouter.Get("/bytes", listUsers).Describe(RouteDoc{Response: openAPIBytes{}})
+	server.Register(testRouter)
+
+	schemas := server.OpenAPI(OpenAPIConfig{}).Components.Schemas
+	properties := schemas["plaudern.openAPIBytes"]["properties"].(map[string]any)
+	utils.AssertEq[any](t, "array", properties["hash"].(Schema)["type"])
+	utils.AssertEq[any](t, "base64", properties["raw"].(Schema)["contentEncoding"])
+	utils.AssertEq[any](t, "string", properties["id"].(Schema)["type"])
+	utils.AssertEq(t, 0, len(properties["extra"].(Schema)))
+}
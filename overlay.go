@@ -0,0 +1,203 @@
+package plaud
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"sort"
+)
+
+// header set on responses of an OverlayFS in debug mode
+const OverlayLayerHeader = "X-Overlay-Layer"
+
+// a named root of an OverlayFS
+type OverlayLayer struct {
+	Name string
+	FS   http.FileSystem
+}
+
+// layer serving a directory on disk
+func DirLayer(name, dir string) OverlayLayer {
+	return OverlayLayer{Name: name, FS: http.Dir(dir)}
+}
+
+// layer serving a fs.FS, embed.FS included
+func FSLayer(name string, fsys fs.FS) OverlayLayer {
+	return OverlayLayer{Name: name, FS: http.FS(fsys)}
+}
+
+// searches its layers in order and serves the first match
+// directory listings merge the entries of every layer
+type OverlayFS struct {
+	layers []OverlayLayer
+	// sets the X-Overlay-Layer header to the layer serving the file
+	Debug bool
+}
+
+// the first layer takes precedence, e.g. the theme before the default assets
+func NewOverlayFS(layers ...OverlayLayer) *OverlayFS {
+	return &OverlayFS{layers: layers}
+}
+
+func (o *OverlayFS) Open(name string) (http.File, error) {
+	file, _, err := o.open(name)
+	return file, err
+}
+
+// name of the layer the file is served from
+func (o *OverlayFS) LayerOf(name string) string {
+	file, layer, err := o.open(name)
+	if err != nil {
+		return ""
+	}
+	file.Close()
+	return layer
+}
+
+func (o *OverlayFS) open(name string) (http.File, string, error) {
+	var dirs []http.File
+	var layer string
+
+	for _, l := range o.layers {
+		file, err := l.FS.Open(name)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			closeAll(dirs)
+			return nil, "", err
+		}
+
+		stat, err := file.Stat()
+		if err != nil {
+			file.Close()
+			closeAll(dirs)
+			return nil, "", err
+		}
+		if !stat.IsDir() {
+			// the directory of an upper layer shadows the file,
+			// which in turn hides the layers below it from the listing
+			if len(dirs) > 0 {
+				file.Close()
+				break
+			}
+			// a file shadows the directories of lower layers
+			return file, l.Name, nil
+		}
+
+		if len(dirs) == 0 {
+			layer = l.Name
+		}
+		dirs = append(dirs, file)
+	}
+
+	if len(dirs) == 0 {
+		return nil, "", &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return &overlayDir{File: dirs[0], layers: dirs, offset: -1}, layer, nil
+}
+
+// the overlay as a fs.FS, usable with ServeFS, fs.WalkDir or templates
+func (o *OverlayFS) FS() fs.FS {
+	return overlayFS{o}
+}
+
+type overlayFS struct {
+	overlay *OverlayFS
+}
+
+func (f overlayFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+
+	file, err := f.overlay.Open("/" + name)
+	if err != nil {
+		return nil, err
+	}
+	stat, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	if stat.IsDir() {
+		return overlayFSDir{file}, nil
+	}
+	return file, nil
+}
+
+// directory of the fs.FS view, implements fs.ReadDirFile
+type overlayFSDir struct {
+	http.File
+}
+
+func (d overlayFSDir) ReadDir(count int) ([]fs.DirEntry, error) {
+	infos, err := d.Readdir(count)
+	entries := make([]fs.DirEntry, len(infos))
+	for i, info := range infos {
+		entries[i] = fs.FileInfoToDirEntry(info)
+	}
+	return entries, err
+}
+
+func closeAll(files []http.File) {
+	for _, file := range files {
+		file.Close()
+	}
+}
+
+// directory present in one or more layers
+type overlayDir struct {
+	http.File
+	layers []http.File
+
+	entries []fs.FileInfo
+	offset  int
+}
+
+func (d *overlayDir) Readdir(count int) ([]fs.FileInfo, error) {
+	if d.offset < 0 {
+		seen := make(map[string]bool)
+		for _, layer := range d.layers {
+			infos, err := layer.Readdir(-1)
+			if err != nil {
+				return nil, err
+			}
+			for _, info := range infos {
+				if !seen[info.Name()] {
+					seen[info.Name()] = true
+					d.entries = append(d.entries, info)
+				}
+			}
+		}
+		sort.Slice(d.entries, func(i, j int) bool {
+			return d.entries[i].Name() < d.entries[j].Name()
+		})
+		d.offset = 0
+	}
+
+	remaining := d.entries[d.offset:]
+	if count <= 0 {
+		d.offset = len(d.entries)
+		return remaining, nil
+	}
+	if len(remaining) == 0 {
+		return nil, io.EOF
+	}
+	if count > len(remaining) {
+		count = len(remaining)
+	}
+	d.offset += count
+	return remaining[:count], nil
+}
+
+func (d *overlayDir) Close() error {
+	var err error
+	for _, layer := range d.layers {
+		if closeErr := layer.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
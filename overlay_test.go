@@ -0,0 +1,113 @@
+package plaud
+
+import (
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"plaudern/utils"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestOverlayFS(t *testing.T) {
+	theme := fstest.MapFS{
+		"css/style.css": {Data: []byte("theme")},
+		"css/theme.css": {Data: []byte("extra")},
+	}
+
+	overlay := NewOverlayFS(FSLayer("theme", theme), DirLayer("default", "./test_files"))
+	overlay.Debug = true
+
+	server := New(":8000")
+	testRouter := NewRouter("/")
+	testRouter.ServeDir("/assets", overlay)
+	server.Register(testRouter)
+
+	tests := []struct {
+		path  string
+		body  string
+		layer string
+	}{
+		{"/assets/css/style.css", "theme", "theme"},
+		{"/assets/js/test.js", "", "default"},
+		{"/assets/nested/", "", "default"},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodGet, test.path, http.NoBody)
+		res := httptest.NewRecorder()
+		server.server.ServeHTTP(res, req)
+
+		utils.AssertEq(t, http.StatusOK, res.Code)
+		utils.AssertEq(t, test.layer, res.Header().Get(OverlayLayerHeader))
+		if test.body != "" {
+			utils.AssertEq(t, test.body, res.Body.String())
+		}
+	}
+
+	// listings merge every layer
+	dir, err := overlay.Open("/css")
+	utils.AssertNoErr(t, err)
+	defer dir.Close()
+	infos, err := dir.Readdir(-1)
+	utils.AssertNoErr(t, err)
+	var names []string
+	for _, info := range infos {
+		names = append(names, info.Name())
+	}
+	utils.AssertEq(t, "style.css,theme.css", strings.Join(names, ","))
+}
+
+func TestOverlayFSShadowing(t *testing.T) {
+	files := fstest.MapFS{
+		"docs":         {Data: []byte("file")},
+		"shared/a.txt": {Data: []byte("a")},
+	}
+	dirs := fstest.MapFS{
+		"docs/index.txt": {Data: []byte("index")},
+		"shared":         {Data: []byte("file below a directory")},
+	}
+	lowest := fstest.MapFS{
+		"shared/b.txt": {Data: []byte("b")},
+	}
+
+	overlay := NewOverlayFS(FSLayer("files", files), FSLayer("dirs", dirs), FSLayer("lowest", lowest))
+	fsys := overlay.FS()
+
+	// a file shadows the directory of a lower layer
+	content, err := fs.ReadFile(fsys, "docs")
+	utils.AssertNoErr(t, err)
+	utils.AssertEq(t, "file", string(content))
+	utils.AssertEq(t, "files", overlay.LayerOf("/docs"))
+
+	// a directory shadows the file of a lower layer, and the layers below that file
+	entries, err := fs.ReadDir(fsys, "shared")
+	utils.AssertNoErr(t, err)
+	utils.AssertEq(t, 1, len(entries))
+	utils.AssertEq(t, "a.txt", entries[0].Name())
+}
+
+func TestOverlayFSView(t *testing.T) {
+	theme := fstest.MapFS{
+		"css/style.css": {Data: []byte("theme")},
+		"css/theme.css": {Data: []byte("extra")},
+	}
+	base := fstest.MapFS{
+		"css/style.css": {Data: []byte("default")},
+		"js/app.js":     {Data: []byte("app")},
+	}
+	fsys := NewOverlayFS(FSLayer("theme", theme), FSLayer("default", base)).FS()
+	utils.AssertNoErr(t, fstest.TestFS(fsys, "css/style.css", "css/theme.css", "js/app.js"))
+
+	server := New(":8000")
+	testRouter := NewRouter("/")
+	testRouter.ServeFS("/assets", fsys)
+	server.Register(testRouter)
+
+	req := httptest.NewRequest(http.MethodGet, "/assets/css/style.css", http.NoBody)
+	res := httptest.NewRecorder()
+	server.server.ServeHTTP(res, req)
+	utils.AssertEq(t, http.StatusOK, res.Code)
+	utils.AssertEq(t, "theme", res.Body.String())
+}
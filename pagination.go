@@ -0,0 +1,227 @@
+package plaud
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// query parameters read by Context.Pagination
+const (
+	PageParam   = "page"
+	LimitParam  = "limit"
+	CursorParam = "cursor"
+	SortParam   = "sort"
+)
+
+// limits of Context.Pagination, zero values use the defaults below
+type PaginationOptions struct {
+	DefaultLimit int
+	// larger limits are lowered to MaxLimit
+	MaxLimit int
+}
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+// requested page of a list
+// offset pagination uses Page and Offset, cursor pagination uses Cursor
+type Pagination struct {
+	// 1 based
+	Page   int
+	Limit  int
+	Offset int
+	// opaque cursor sent back by the client, empty for the first page
+	Cursor string
+}
+
+// parses ?page=2&limit=20 or ?cursor=abc&limit=20
+func (c *Context) Pagination(opts PaginationOptions) (Pagination, *Error) {
+	if opts.DefaultLimit <= 0 {
+		opts.DefaultLimit = defaultPageLimit
+	}
+	if opts.MaxLimit <= 0 {
+		opts.MaxLimit = maxPageLimit
+	}
+
+	query := c.Request.URL.Query()
+	p := Pagination{Page: 1, Limit: opts.DefaultLimit, Cursor: query.Get(CursorParam)}
+
+	if raw := query.Get(PageParam); raw != "" {
+		page, err := strconv.Atoi(raw)
+		if err != nil || page < 1 {
+			return p, NewError("Invalid page").SetCode(http.StatusBadRequest)
+		}
+		p.Page = page
+	}
+	if raw := query.Get(LimitParam); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 {
+			return p, NewError("Invalid limit").SetCode(http.StatusBadRequest)
+		}
+		p.Limit = min(limit, opts.MaxLimit)
+	}
+	// the offset would overflow
+	if p.Page-1 > math.MaxInt/p.Limit {
+		return p, NewError("Invalid page").SetCode(http.StatusBadRequest)
+	}
+
+	p.Offset = (p.Page - 1) * p.Limit
+	return p, nil
+}
+
+// allow-lists of Context.ListQuery
+// fields not listed are rejected with 400
+type ListOptions struct {
+	Pagination PaginationOptions
+	Sortable   []string
+	Filterable []string
+}
+
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+// parsed list request
+// ?page=2&sort=-created_at,name&filter[status]=active
+type ListQuery struct {
+	Pagination
+	Sort []SortField
+	// filter[field] -> values, repeated parameters give several values
+	Filters map[string][]string
+}
+
+func (c *Context) ListQuery(opts ListOptions) (*ListQuery, *Error) {
+	pagination, err := c.Pagination(opts.Pagination)
+	if err != nil {
+		return nil, err
+	}
+	list := &ListQuery{Pagination: pagination, Filters: make(map[string][]string)}
+
+	query := c.Request.URL.Query()
+	for _, raw := range strings.Split(query.Get(SortParam), ",") {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+
+		sort := SortField{Field: strings.TrimPrefix(raw, "-"), Desc: strings.HasPrefix(raw, "-")}
+		if !slices.Contains(opts.Sortable, sort.Field) {
+			return nil, NewError(fmt.Sprintf("Unknown sort field %s", sort.Field)).SetCode(http.StatusBadRequest)
+		}
+		list.Sort = append(list.Sort, sort)
+	}
+
+	for key, values := range query {
+		field, ok := strings.CutPrefix(key, "filter[")
+		if !ok {
+			continue
+		}
+		field, ok = strings.CutSuffix(field, "]")
+		if !ok || !slices.Contains(opts.Filterable, field) {
+			return nil, NewError(fmt.Sprintf("Unknown filter %s", key)).SetCode(http.StatusBadRequest)
+		}
+		list.Filters[field] = values
+	}
+
+	return list, nil
+}
+
+// page of a list response, see Data.SetPage
+type Page struct {
+	Limit int
+	// offset pagination, Number is 1 based
+	Number int
+	Total  int
+	// cursor pagination, empty when there is no next or previous page
+	NextCursor string
+	PrevCursor string
+}
+
+func OffsetPage(p Pagination, total int) Page {
+	return Page{Limit: p.Limit, Number: p.Page, Total: total}
+}
+
+func CursorPage(p Pagination, next, prev string) Page {
+	return Page{Limit: p.Limit, NextCursor: next, PrevCursor: prev}
+}
+
+func (p Page) isOffset() bool {
+	return p.Number > 0
+}
+
+// number of pages of an offset page
+func (p Page) Pages() int {
+	if p.Limit <= 0 {
+		return 0
+	}
+	return (p.Total + p.Limit - 1) / p.Limit
+}
+
+// adds the pagination meta and the first/prev/next/last Link headers
+func (e *Data) SetPage(page Page) *Data {
+	e.page = &page
+	return e
+}
+
+func (p Page) meta() map[string]any {
+	if p.isOffset() {
+		return map[string]any{
+			"page":  p.Number,
+			"limit": p.Limit,
+			"total": p.Total,
+			"pages": p.Pages(),
+		}
+	}
+
+	meta := map[string]any{"limit": p.Limit}
+	if p.NextCursor != "" {
+		meta["next_cursor"] = p.NextCursor
+	}
+	if p.PrevCursor != "" {
+		meta["prev_cursor"] = p.PrevCursor
+	}
+	return meta
+}
+
+// links relative to the requested url, the other query parameters are kept
+func (p Page) links(requestURL *url.URL) []Link {
+	link := func(rel, param, value string) Link {
+		query := requestURL.Query()
+		query.Del(PageParam)
+		query.Del(CursorParam)
+		if value != "" {
+			query.Set(param, value)
+		}
+		query.Set(LimitParam, strconv.Itoa(p.Limit))
+		return Link{URL: requestURL.Path + "?" + query.Encode(), Rel: rel}
+	}
+
+	if !p.isOffset() {
+		links := []Link{link("first", CursorParam, "")}
+		if p.PrevCursor != "" {
+			links = append(links, link("prev", CursorParam, p.PrevCursor))
+		}
+		if p.NextCursor != "" {
+			links = append(links, link("next", CursorParam, p.NextCursor))
+		}
+		return links
+	}
+
+	pages := max(p.Pages(), 1)
+	links := []Link{link("first", PageParam, "1")}
+	if p.Number > 1 {
+		links = append(links, link("prev", PageParam, strconv.Itoa(min(p.Number-1, pages))))
+	}
+	if p.Number < pages {
+		links = append(links, link("next", PageParam, strconv.Itoa(p.Number+1)))
+	}
+	return append(links, link("last", PageParam, strconv.Itoa(pages)))
+}
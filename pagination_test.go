@@ -0,0 +1,101 @@
+package plaud
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"plaudern/utils"
+	"strings"
+	"testing"
+)
+
+func TestListQuery(t *testing.T) {
+	opts := ListOptions{
+		Pagination: PaginationOptions{DefaultLimit: 10, MaxLimit: 50},
+		Sortable:   []string{"created_at", "name"},
+		Filterable: []string{"status"},
+	}
+
+	tests := []struct {
+		query string
+		code  int
+	}{
+		{"?page=0", http.StatusBadRequest},
+		{"?page=9223372036854775807&limit=100", http.StatusBadRequest},
+		{"?limit=ten", http.StatusBadRequest},
+		{"?sort=password", http.StatusBadRequest},
+		{"?filter[role]=admin", http.StatusBadRequest},
+		{"?filter=admin", http.StatusOK},
+	}
+	for _, test := range tests {
+		ctx := NewContext(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users"+test.query, nil))
+		_, err := ctx.ListQuery(opts)
+		if test.code == http.StatusOK {
+			utils.AssertEq(t, nil, err)
+			continue
+		}
+		utils.AssertEq(t, test.code, err.Code())
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/users?page=3&limit=500&sort=-created_at,name&filter[status]=active&filter[status]=invited", nil)
+	list, err := NewContext(httptest.NewRecorder(), req).ListQuery(opts)
+	utils.AssertEq(t, nil, err)
+	utils.AssertEq(t, 3, list.Page)
+	utils.AssertEq(t, 50, list.Limit)
+	utils.AssertEq(t, 100, list.Offset)
+	utils.AssertEq(t, 2, len(list.Sort))
+	utils.AssertEq(t, SortField{Field: "created_at", Desc: true}, list.Sort[0])
+	utils.AssertEq(t, SortField{Field: "name"}, list.Sort[1])
+	utils.AssertEq(t, "active,invited", strings.Join(list.Filters["status"], ","))
+}
+
+func TestSetPage(t *testing.T) {
+	server := New(":8000")
+	testRouter := NewRouter("/")
+	testRouter.Get("/users", func(ctx *Context) (*Data, *Error) {
+		pagination, err := ctx.Pagination(PaginationOptions{})
+		if err != nil {
+			return nil, err
+		}
+		return NewData("Users").SetData([]string{}).SetPage(OffsetPage(pagination, 95)), nil
+	})
+	testRouter.Get("/events", func(ctx *Context) (*Data, *Error) {
+		pagination, err := ctx.Pagination(PaginationOptions{})
+		if err != nil {
+			return nil, err
+		}
+		return NewData("Events").SetPage(CursorPage(pagination, "b2Zmc2V0PTQw", pagination.Cursor)), nil
+	})
+	server.Register(testRouter)
+
+	req := httptest.NewRequest(http.MethodGet, "/users?page=2&sort=name", nil)
+	res := httptest.NewRecorder()
+	server.server.ServeHTTP(res, req)
+
+	utils.AssertEq(t, strings.Join([]string{
+		`</users?limit=20&page=1&sort=name>; rel="first"`,
+		`</users?limit=20&page=1&sort=name>; rel="prev"`,
+		`</users?limit=20&page=3&sort=name>; rel="next"`,
+		`</users?limit=20&page=5&sort=name>; rel="last"`,
+	}, ","), strings.Join(res.Header().Values("Link"), ","))
+
+	var body struct {
+		Meta struct {
+			Pagination map[string]int `json:"pagination"`
+		} `json:"meta"`
+	}
+	utils.AssertNoErr(t, json.NewDecoder(res.Body).Decode(&body))
+	utils.AssertEq(t, 2, body.Meta.Pagination["page"])
+	utils.AssertEq(t, 95, body.Meta.Pagination["total"])
+	utils.AssertEq(t, 5, body.Meta.Pagination["pages"])
+
+	req = httptest.NewRequest(http.MethodGet, "/events?cursor=b2Zmc2V0PTIw", nil)
+	res = httptest.NewRecorder()
+	server.server.ServeHTTP(res, req)
+
+	utils.AssertEq(t, strings.Join([]string{
+		`</events?limit=20>; rel="first"`,
+		`</events?cursor=b2Zmc2V0PTIw&limit=20>; rel="prev"`,
+		`</events?cursor=b2Zmc2V0PTQw&limit=20>; rel="next"`,
+	}, ","), strings.Join(res.Header().Values("Link"), ","))
+}
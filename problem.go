@@ -0,0 +1,146 @@
+package plaud
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// how errors are written to the client
+type ErrorFormat int
+
+const (
+	// {"data": ..., "message": ...}, the default
+	EnvelopeErrors ErrorFormat = iota + 1
+	// RFC 9457 application/problem+json
+	ProblemDetails
+)
+
+const problemContentType = "application/problem+json"
+
+// RFC 9457 problem details
+// extension members are written next to the standard ones
+type Problem struct {
+	Type       string         `json:"type"`
+	Title      string         `json:"title"`
+	Status     int            `json:"status"`
+	Detail     string         `json:"detail,omitempty"`
+	Instance   string         `json:"instance,omitempty"`
+	Extensions map[string]any `json:"-"`
+}
+
+func (p Problem) MarshalJSON() ([]byte, error) {
+	members := make(map[string]any, len(p.Extensions)+5)
+	for key, value := range p.Extensions {
+		members[key] = value
+	}
+
+	// the standard members can't be overridden by extensions
+	members["type"] = p.Type
+	members["title"] = p.Title
+	members["status"] = p.Status
+	if p.Detail != "" {
+		members["detail"] = p.Detail
+	}
+	if p.Instance != "" {
+		members["instance"] = p.Instance
+	}
+	return json.Marshal(members)
+}
+
+// problem details of the error
+// Data and ErrorCode are added as the "data" and "code" extensions
+func (e *Error) Problem() Problem {
+	problem := Problem{
+		Type:     e.problemType,
+		Title:    e.title,
+		Status:   e.code,
+		Detail:   e.detail,
+		Instance: e.instance,
+	}
+	if problem.Type == "" {
+		problem.Type = "about:blank"
+	}
+	if problem.Title == "" {
+		problem.Title = http.StatusText(e.code)
+	}
+	if problem.Detail == "" {
+		problem.Detail = e.Message
+	}
+
+	if len(e.extensions) > 0 || e.Data != nil || e.ErrorCode != "" {
+		problem.Extensions = make(map[string]any, len(e.extensions)+2)
+		if e.Data != nil {
+			problem.Extensions["data"] = e.Data
+		}
+		if e.ErrorCode != "" {
+			problem.Extensions["code"] = e.ErrorCode
+		}
+		for key, value := range e.extensions {
+			problem.Extensions[key] = value
+		}
+	}
+	return problem
+}
+
+// error format, error handler and envelope of a server, router or route
+// zero values are inherited from the enclosing router, then the server
+type responseConfig struct {
+	format   ErrorFormat
+	handler  ErrorHandler
+	envelope Envelope
+}
+
+// fills the unset fields from parent
+func (c responseConfig) inherit(parent responseConfig) responseConfig {
+	if c.format == 0 {
+		c.format = parent.format
+	}
+	if c.handler == nil {
+		c.handler = parent.handler
+	}
+	if c.envelope == nil {
+		c.envelope = parent.envelope
+	}
+	return c
+}
+
+// renders errors of the server's routes in the given format
+// routers can override it with Router.SetErrorFormat
+func (s *Server) SetErrorFormat(format ErrorFormat) *Server {
+	s.config.format = format
+	return s
+}
+
+// renders errors of the router's routes in the given format
+// applies to the routers nested in it that don't set their own
+func (r *Router) SetErrorFormat(format ErrorFormat) HTTPRouter {
+	r.config.format = format
+	return r
+}
+
+func (r *Router) getResponseConfig() responseConfig {
+	return r.config
+}
+
+func (c *Context) responseConfig() responseConfig {
+	if c.server == nil {
+		return c.config
+	}
+	return c.config.inherit(c.server.config)
+}
+
+// writes the error in the format configured for the route
+// used by error handlers
+func (c *Context) RenderError(err *Error) {
+	if c.responseConfig().format != ProblemDetails {
+		c.JSON(err.code, err)
+		return
+	}
+
+	c.ResponseWriter.Header().Set("Content-Type", problemContentType)
+	c.Status(err.code)
+	if encodeErr := json.NewEncoder(c.ResponseWriter).Encode(err.Problem()); encodeErr != nil {
+		c.Errors = append(c.Errors, NewError("Failed to encode JSON").SetCode(http.StatusInternalServerError))
+	}
+	c.Abort()
+}
@@ -0,0 +1,83 @@
+package plaud
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"plaudern/utils"
+	"testing"
+)
+
+func TestProblemMarshal(t *testing.T) {
+	err := NewError("Balance too low").
+		SetCode(http.StatusForbidden).
+		SetType("https://example.com/probs/out-of-credit").
+		SetInstance("/account/12345/msgs/abc").
+		SetExtension("balance", 30).
+		SetExtension("status", 200)
+
+	encoded, marshalErr := json.Marshal(err.Problem())
+	utils.AssertNoErr(t, marshalErr)
+
+	var members map[string]any
+	utils.AssertNoErr(t, json.Unmarshal(encoded, &members))
+	utils.AssertEq[any](t, "https://example.com/probs/out-of-credit", members["type"])
+	utils.AssertEq[any](t, "Forbidden", members["title"])
+	// extensions don't override the standard members
+	utils.AssertEq[any](t, float64(http.StatusForbidden), members["status"])
+	utils.AssertEq[any](t, "Balance too low", members["detail"])
+	utils.AssertEq[any](t, "/account/12345/msgs/abc", members["instance"])
+	utils.AssertEq[any](t, float64(30), members["balance"])
+}
+
+func TestProblemDetailsFormat(t *testing.T) {
+	failing := func(ctx *Context) (*Data, *Error) {
+		return nil, NewError("User not found").SetCode(http.StatusNotFound).SetData("Jotaro")
+	}
+
+	server := New(":8000").SetErrorFormat(ProblemDetails)
+	apiRouter := NewRouter("/api")
+	apiRouter.Get("/users", failing)
+
+	legacyRouter := NewRouter("")
+	legacyRouter.SetErrorFormat(EnvelopeErrors)
+	legacyRouter.Get("/users", failing)
+	apiRouter.Handle("/legacy", legacyRouter)
+
+	denied := NewRouter("/denied")
+	denied.Use(func(ctx *Context) *Error {
+		return ctx.AbortWithError("Denied", http.StatusUnauthorized)
+	})
+	denied.Get("/", failing)
+	server.Register(apiRouter, denied)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/users", nil)
+	res := httptest.NewRecorder()
+	server.server.ServeHTTP(res, req)
+	utils.AssertEq(t, http.StatusNotFound, res.Code)
+	utils.AssertEq(t, "application/problem+json", res.Header().Get("Content-Type"))
+
+	var problem map[string]any
+	utils.AssertNoErr(t, json.NewDecoder(res.Body).Decode(&problem))
+	utils.AssertEq[any](t, "about:blank", problem["type"])
+	utils.AssertEq[any](t, "Not Found", problem["title"])
+	utils.AssertEq[any](t, "User not found", problem["detail"])
+	utils.AssertEq[any](t, "Jotaro", problem["data"])
+
+	// nested routers keep their own format
+	req = httptest.NewRequest(http.MethodGet, "/api/legacy/users", nil)
+	res = httptest.NewRecorder()
+	server.server.ServeHTTP(res, req)
+	utils.AssertEq(t, "application/json", res.Header().Get("Content-Type"))
+
+	var envelope Error
+	utils.AssertNoErr(t, json.NewDecoder(res.Body).Decode(&envelope))
+	utils.AssertEq(t, "User not found", envelope.Message)
+
+	// middleware errors use the format too
+	req = httptest.NewRequest(http.MethodGet, "/denied", nil)
+	res = httptest.NewRecorder()
+	server.server.ServeHTTP(res, req)
+	utils.AssertEq(t, http.StatusUnauthorized, res.Code)
+	utils.AssertEq(t, "application/problem+json", res.Header().Get("Content-Type"))
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"slices"
 	"strings"
 )
 
@@ -11,11 +12,13 @@ type HTTPMethod string
 
 // allowed methods
 const (
-	GET    HTTPMethod = "GET"
-	POST   HTTPMethod = "POST"
-	PUT    HTTPMethod = "PUT"
-	PATCH  HTTPMethod = "PATCH"
-	DELETE HTTPMethod = "DELETE"
+	GET     HTTPMethod = "GET"
+	POST    HTTPMethod = "POST"
+	PUT     HTTPMethod = "PUT"
+	PATCH   HTTPMethod = "PATCH"
+	DELETE  HTTPMethod = "DELETE"
+	HEAD    HTTPMethod = "HEAD"
+	OPTIONS HTTPMethod = "OPTIONS"
 )
 
 // leaf node of a router
@@ -29,8 +32,19 @@ type HTTPRoute interface {
 
 	// registers the routers middlewares to the route
 	stackMiddleware([]MiddleWareFunc)
+	// binds the route to the server it is registered with
+	setServer(*Server)
+	// fills the response settings the route doesn't have from its router
+	inheritConfig(responseConfig)
 	// registers route specific middleware
 	Use(...MiddleWareFunc)
+
+	// describes the route for introspection
+	Info() RouteInfo
+	// names the route for reverse url generation, see Server.URL
+	Name(string) HTTPRoute
+	// documents the route for the generated OpenAPI document
+	Describe(RouteDoc) HTTPRoute
 }
 
 // handles the URI of the api which is to be registered with the Router
@@ -39,6 +53,15 @@ type Route struct {
 	path        string
 	httpfunc    HTTPFunc
 	middlewares []MiddleWareFunc
+	server      *Server
+	name        string
+	doc         RouteDoc
+	config      responseConfig
+
+	// path of the router the route was created on
+	router string
+	// reported instead of the name of httpfunc when it wraps another function
+	handlerName string
 }
 
 func (route *Route) GetRoute() string {
@@ -47,37 +70,25 @@ func (route *Route) GetRoute() string {
 
 // return the http handler for the routes
 // handles the encoding (json,grpc...)
-//
-// TODO: Error handling will be added in a future commit
+// errors are rendered by the route's ErrorHandler
 func (route *Route) GetHandleFunc() func(http.ResponseWriter, *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := NewContext(w, r)
+		ctx.server = route.server
+		ctx.config = route.config
 
-		handlers := make([]MiddleWareFunc, len(route.middlewares)+1)
-		copy(handlers, route.middlewares)
-
-		handlers[len(route.middlewares)] = func(ctx *Context) *Error {
+		runChain(ctx, route.middlewares, func(ctx *Context) *Error {
 			data, err := route.httpfunc(ctx)
 			if err != nil {
-				ctx.JSON(err.code, err)
 				return err
 			}
 
 			if data != nil {
-				ctx.JSON(data.code, data)
+				return ctx.renderData(data)
 			}
 
 			return nil
-		}
-		ctx.SetMiddlewares(handlers)
-		// handling middlewares
-		ctx.Next()
-		if len(ctx.Errors) > 0 {
-			err := ctx.Errors[len(ctx.Errors)-1]
-			// TODO: handle error below
-			// have a default logger with the router
-			ctx.JSON(err.code, err)
-		}
+		})
 	}
 }
 
@@ -100,7 +111,43 @@ func NewRoute(method HTTPMethod, path string, httpfunc HTTPFunc) (*Route, error)
 }
 
 func (route *Route) stackMiddleware(middleware []MiddleWareFunc) {
-	route.middlewares = append(middleware, route.middlewares...)
+	route.middlewares = slices.Concat(middleware, route.middlewares)
+}
+
+func (route *Route) Info() RouteInfo {
+	handler := route.handlerName
+	if handler == "" {
+		handler = funcName(route.httpfunc)
+	}
+
+	return RouteInfo{
+		Method:      string(route.method),
+		Pattern:     route.path,
+		Name:        route.name,
+		Handler:     handler,
+		Middlewares: middlewareNames(route.middlewares),
+		Router:      route.router,
+		Doc:         route.doc,
+		config:      route.config,
+	}
+}
+
+func (route *Route) Name(name string) HTTPRoute {
+	route.name = name
+	return route
+}
+
+func (route *Route) Describe(doc RouteDoc) HTTPRoute {
+	route.doc = doc.merge(route.doc)
+	return route
+}
+
+func (route *Route) setServer(server *Server) {
+	route.server = server
+}
+
+func (route *Route) inheritConfig(config responseConfig) {
+	route.config = route.config.inherit(config)
 }
 
 // registers a set of all middlewares
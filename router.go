@@ -2,6 +2,7 @@ package plaud
 
 import (
 	"fmt"
+	"io/fs"
 	"log/slog"
 	"net/http"
 	"strings"
@@ -22,7 +23,22 @@ type HTTPRouter interface {
 
 	// static files
 	// serves the contents of the directory
-	ServeDir(string, http.FileSystem) HTTPRoute
+	ServeDir(string, http.FileSystem, ...FileHandlerOption) HTTPRoute
+	// serves the contents of a fs.FS, embed.FS included
+	ServeFS(string, fs.FS, ...FileHandlerOption) HTTPRoute
+	// serves a single file
+	ServeFile(string, string) HTTPRoute
+
+	// server-sent events
+	// streams events to the client until the function returns
+	SSE(string, SSEFunc) HTTPRoute
+
+	// websockets
+	// upgrades the request after the middlewares ran
+	WebSocket(string, WebSocketFunc, ...WebSocketOption) HTTPRoute
+
+	// resumable uploads using the tus protocol
+	TusUpload(string, TusStore, TusOptions) RouteGroup
 
 	// returns all the routes
 	GetRoutes() []HTTPRoute
@@ -43,6 +59,22 @@ type HTTPRouter interface {
 	// applied to every route registered within the router
 	// takes precedence over the middleware within the route
 	Use(...MiddleWareFunc) HTTPRouter
+
+	// renders the errors of the router's routes in the given format
+	SetErrorFormat(ErrorFormat) HTTPRouter
+	// handles the errors of the router's routes
+	SetErrorHandler(ErrorHandler) HTTPRouter
+	// wraps the responses of the router's routes
+	SetEnvelope(Envelope) HTTPRouter
+}
+
+// implemented by Router, lets Handle pass the middlewares and response settings
+// of a nested router on to its routes
+// kept off HTTPRouter so other packages can implement it,
+// routers not implementing it are nested without them
+type nestedRouter interface {
+	getMiddlewares() []MiddleWareFunc
+	getResponseConfig() responseConfig
 }
 
 // router contains a group of routes
@@ -53,6 +85,7 @@ type Router struct {
 
 	fileHandlers []HTTPRoute
 	middlewares  []MiddleWareFunc
+	config       responseConfig
 }
 
 func NewRouter(path string) *Router {
@@ -68,11 +101,21 @@ func (r *Router) createRoute(method HTTPMethod, path string, httpFunc HTTPFunc)
 		slog.Error("Invalid route", "path", path)
 		return nil
 	}
+	route.router = r.routerPath()
 	r.routes = append(r.routes, route)
 
 	return route
 }
 
+// creates a route whose httpFunc wraps fn, fn is reported as the handler
+func (r *Router) createWrappedRoute(method HTTPMethod, path string, fn any, httpFunc HTTPFunc) HTTPRoute {
+	route := r.createRoute(method, path, httpFunc)
+	if route, ok := route.(*Route); ok {
+		route.handlerName = funcName(fn)
+	}
+	return route
+}
+
 func (r *Router) Get(path string, httpFunc HTTPFunc) HTTPRoute {
 	return r.createRoute(GET, path, httpFunc)
 }
@@ -93,13 +136,29 @@ func (r *Router) Delete(path string, httpFunc HTTPFunc) HTTPRoute {
 	return r.createRoute(DELETE, path, httpFunc)
 }
 
-func (r *Router) ServeDir(path string, dir http.FileSystem) HTTPRoute {
+func (r *Router) ServeDir(path string, dir http.FileSystem, opts ...FileHandlerOption) HTTPRoute {
 	path = strings.TrimRight(path, "/")
-	handler := NewFileHandler(dir, r.path+path)
+	handler := NewFileHandler(dir, r.path+path, opts...)
+	handler.router = r.routerPath()
 	r.fileHandlers = append(r.fileHandlers, handler)
 	return handler
 }
 
+func (r *Router) ServeFS(path string, fsys fs.FS, opts ...FileHandlerOption) HTTPRoute {
+	path = strings.TrimRight(path, "/")
+	handler := NewFileHandlerFS(fsys, r.path+path, opts...)
+	handler.router = r.routerPath()
+	r.fileHandlers = append(r.fileHandlers, handler)
+	return handler
+}
+
+func (r *Router) routerPath() string {
+	if r.path == "" {
+		return "/"
+	}
+	return r.path
+}
+
 func (r *Router) GetRoutes() []HTTPRoute {
 	return r.routes
 }
@@ -118,15 +177,26 @@ func (r *Router) Handle(path string, router HTTPRouter) {
 	r.path = strings.TrimRight(r.path, "/")
 	r.path = fmt.Sprintf("%s%s", r.path, path)
 
+	// the middlewares of this router are stacked in RegisterServer,
+	// or by the router this one gets nested in
+	var middlewares []MiddleWareFunc
+	var config responseConfig
+	if nested, ok := router.(nestedRouter); ok {
+		middlewares = nested.getMiddlewares()
+		config = nested.getResponseConfig()
+	}
+
 	for _, route := range router.GetRoutes() {
 		route.Prepend(r.path)
-		route.stackMiddleware(r.middlewares)
+		route.stackMiddleware(middlewares)
+		route.inheritConfig(config)
 		r.routes = append(r.routes, route)
 	}
 
 	for _, route := range router.GetHandlers() {
 		route.Prepend(r.path)
-		route.stackMiddleware(r.middlewares)
+		route.stackMiddleware(middlewares)
+		route.inheritConfig(config)
 		r.fileHandlers = append(r.fileHandlers, route)
 	}
 }
@@ -139,22 +209,25 @@ func (r *Router) RegisterServer(mux *http.ServeMux) {
 	for _, route := range r.routes {
 		slog.Info("Api Route", "route", route.GetRoute())
 		route.stackMiddleware(r.middlewares)
+		route.inheritConfig(r.config)
 		mux.HandleFunc(route.GetRoute(), route.GetHandleFunc())
 	}
 	for _, handler := range r.fileHandlers {
 		handler.stackMiddleware(r.middlewares)
-
-		// handle routes without trailing
-		// TODO: danger ahead
-		// if handler.GetRoute() != "/" {
-		// 	handlePath:=strings.TrimRight(handler.GetRoute(), "/")
-		// 	slog.Info("Registered", "path", handlePath)
-		// 	mux.HandleFunc(handlePath, func(w http.ResponseWriter, r *http.Request) {
-		// 		http.StripPrefix(handlePath, handler.GetHandler()).ServeHTTP(w, r)
-		// 	})
-		// }
-
-		mux.Handle(handler.GetRoute(), http.StripPrefix(handler.GetRoute(), handler.GetHandler()))
+		handler.inheritConfig(r.config)
+		if fileHandler, ok := handler.(*FileHandler); ok && fileHandler.spaIndex != "" {
+			fileHandler.setAPIRoutes(r.routes)
+		}
+
+		mux.HandleFunc(handler.GetRoute(), handler.GetHandleFunc())
+
+		// handle routes without trailing '/'
+		// the mux redirects them unless the handler asks otherwise
+		if fileHandler, ok := handler.(*FileHandler); ok {
+			if path, handleFunc := fileHandler.bareHandleFunc(); handleFunc != nil {
+				mux.HandleFunc(path, handleFunc)
+			}
+		}
 	}
 }
 
@@ -164,3 +237,7 @@ func (r *Router) Use(middlewares ...MiddleWareFunc) HTTPRouter {
 	r.middlewares = append(r.middlewares, middlewares...)
 	return r
 }
+
+func (r *Router) getMiddlewares() []MiddleWareFunc {
+	return r.middlewares
+}
@@ -0,0 +1,170 @@
+package plaud
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// interval between the keep alive comments sent on an idle stream
+var DefaultHeartbeat = 15 * time.Second
+
+var ErrStreamClosed = errors.New("event stream closed")
+
+// type of the function that handles a server-sent events stream
+// the stream is closed once the function returns
+type SSEFunc func(*Context, *EventStream) error
+
+// a single server-sent event
+// empty fields are not written
+type Event struct {
+	ID    string
+	Event string
+	Data  string
+	Retry time.Duration
+}
+
+// writes server-sent events to the client
+// safe to be used from multiple goroutines
+type EventStream struct {
+	ctx        *Context
+	controller *http.ResponseController
+	lastID     string
+
+	mu     sync.Mutex
+	closed bool
+	done   chan struct{}
+}
+
+// switches the response to a text/event-stream and starts the heartbeat
+// the caller should Close the stream when done
+func (c *Context) SSE() (*EventStream, *Error) {
+	stream := &EventStream{
+		ctx:        c,
+		controller: http.NewResponseController(c.ResponseWriter),
+		lastID:     c.Request.Header.Get("Last-Event-ID"),
+		done:       make(chan struct{}),
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	if err := stream.controller.Flush(); err != nil {
+		return nil, NewError("Streaming not supported").SetCode(http.StatusInternalServerError)
+	}
+
+	go stream.heartbeat(DefaultHeartbeat)
+
+	return stream, nil
+}
+
+// id of the last event received by the client, used to resume a stream
+func (s *EventStream) LastEventID() string {
+	return s.lastID
+}
+
+// closed when the client disconnects, the server shuts down or the stream is closed
+func (s *EventStream) Done() <-chan struct{} {
+	return s.done
+}
+
+// writes and flushes a single event
+func (s *EventStream) Send(event Event) error {
+	var sb strings.Builder
+	if event.ID != "" {
+		fmt.Fprintf(&sb, "id: %s\n", stripNewlines(event.ID))
+	}
+	if event.Event != "" {
+		fmt.Fprintf(&sb, "event: %s\n", stripNewlines(event.Event))
+	}
+	if event.Retry > 0 {
+		fmt.Fprintf(&sb, "retry: %d\n", event.Retry.Milliseconds())
+	}
+	// \r\n, \r and \n all end a line for the client
+	data := strings.NewReplacer("\r\n", "\n", "\r", "\n").Replace(event.Data)
+	for _, line := range strings.Split(data, "\n") {
+		fmt.Fprintf(&sb, "data: %s\n", line)
+	}
+	sb.WriteString("\n")
+
+	return s.write(sb.String())
+}
+
+// writes a comment line, ignored by the client
+func (s *EventStream) Comment(comment string) error {
+	return s.write(fmt.Sprintf(": %s\n\n", stripNewlines(comment)))
+}
+
+// stops the heartbeat, no events can be sent afterwards
+func (s *EventStream) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.closed {
+		s.closed = true
+		close(s.done)
+	}
+}
+
+func (s *EventStream) write(frame string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return ErrStreamClosed
+	}
+	if err := s.ctx.Request.Context().Err(); err != nil {
+		return err
+	}
+
+	if _, err := s.ctx.Write([]byte(frame)); err != nil {
+		return err
+	}
+	return s.controller.Flush()
+}
+
+func (s *EventStream) heartbeat(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.Comment("heartbeat"); err != nil {
+				s.Close()
+				return
+			}
+		case <-s.ctx.Request.Context().Done():
+			s.Close()
+			return
+		case <-s.done:
+			return
+		}
+	}
+}
+
+func stripNewlines(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
+
+// registers a GET route serving a server-sent events stream
+// errors returned after the stream started can't be sent to the client, they are logged
+func (r *Router) SSE(path string, sseFunc SSEFunc) HTTPRoute {
+	return r.createWrappedRoute(GET, path, sseFunc, func(ctx *Context) (*Data, *Error) {
+		stream, err := ctx.SSE()
+		if err != nil {
+			return nil, err
+		}
+		defer stream.Close()
+
+		if err := sseFunc(ctx, stream); err != nil && !errors.Is(err, ErrStreamClosed) {
+			slog.Error("Event stream failed", "path", ctx.Request.URL.Path, "error", err)
+		}
+		return nil, nil
+	})
+}
@@ -0,0 +1,61 @@
+package plaud
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"plaudern/utils"
+	"testing"
+	"time"
+)
+
+func TestSSE(t *testing.T) {
+	server := New(":8000")
+	testRouter := NewRouter("/")
+	testRouter.SSE("/events", func(_ *Context, stream *EventStream) error {
+		if stream.LastEventID() != "41" {
+			t.Fatalf("Expected Last-Event-ID 41 Got: %s", stream.LastEventID())
+		}
+		if err := stream.Send(Event{ID: "42", Event: "update", Data: "line1\nline2\r\nline3\rid: 9"}); err != nil {
+			return err
+		}
+		return stream.Send(Event{Data: "done", Retry: time.Second})
+	})
+	server.Register(testRouter)
+
+	req := httptest.NewRequest(http.MethodGet, "/events", http.NoBody)
+	req.Header.Set("Last-Event-ID", "41")
+	res := httptest.NewRecorder()
+
+	server.server.ServeHTTP(res, req)
+
+	utils.AssertEq(t, http.StatusOK, res.Code)
+	utils.AssertEq(t, "text/event-stream", res.Header().Get("Content-Type"))
+	expected := "id: 42\nevent: update\ndata: line1\ndata: line2\ndata: line3\ndata: id: 9\n\nretry: 1000\ndata: done\n\n"
+	utils.AssertEq(t, expected, res.Body.String())
+}
+
+func TestSSEClientDisconnect(t *testing.T) {
+	server := New(":8000")
+	testRouter := NewRouter("/")
+	finished := make(chan struct{})
+	testRouter.SSE("/events", func(_ *Context, stream *EventStream) error {
+		defer close(finished)
+		<-stream.Done()
+		return stream.Send(Event{Data: "too late"})
+	})
+	server.Register(testRouter)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequestWithContext(ctx, http.MethodGet, "/events", http.NoBody)
+	res := httptest.NewRecorder()
+
+	go server.server.ServeHTTP(res, req)
+	cancel()
+
+	select {
+	case <-finished:
+	case <-time.After(time.Second):
+		t.Fatal("Event stream did not end after the client disconnected")
+	}
+}
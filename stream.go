@@ -0,0 +1,256 @@
+package plaud
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"log/slog"
+	"net/http"
+)
+
+// encoding used when streaming a sequence
+type StreamFormat int
+
+const (
+	// newline delimited json, one value per line
+	NDJSON StreamFormat = iota
+	// a single json array written element by element
+	JSONArray
+	// comma separated values, elements must be []string or implement CSVRecord
+	CSV
+)
+
+// implemented by types streamed as CSV rows
+type CSVRecord interface {
+	CSVRecord() []string
+}
+
+// streams the sequence to the client, flushing after every element
+// once the first element is written the status is committed,
+// later errors are logged and abort the response so the client sees a broken body
+// instead of a complete looking one
+func Stream[T any](ctx *Context, format StreamFormat, seq iter.Seq[T]) *Error {
+	return Stream2(ctx, format, func(yield func(T, error) bool) {
+		for item := range seq {
+			if !yield(item, nil) {
+				return
+			}
+		}
+	})
+}
+
+// same as Stream for sequences that can fail
+// an error before the first element is returned to the caller as a 500, wrapping it
+func Stream2[T any](ctx *Context, format StreamFormat, seq iter.Seq2[T, error]) *Error {
+	writer, err := newStreamWriter(ctx, format)
+	if err != nil {
+		return err
+	}
+
+	for item, err := range seq {
+		if err != nil {
+			if !writer.started {
+				return WrapError(err)
+			}
+			abortStream(ctx, err)
+		}
+		if err := writer.write(item); err != nil {
+			if !writer.started {
+				return NewError("Failed to encode stream").SetCode(http.StatusInternalServerError).Wrap(err)
+			}
+			abortStream(ctx, err)
+		}
+		if ctx.Request.Context().Err() != nil {
+			return nil
+		}
+	}
+
+	if err := writer.close(); err != nil {
+		slog.Error("Stream failed", "path", ctx.Request.URL.Path, "error", err)
+	}
+	return nil
+}
+
+// the stream can't be terminated properly, the connection is closed without it
+func abortStream(ctx *Context, err error) {
+	slog.Error("Stream failed", "path", ctx.Request.URL.Path, "error", err)
+	panic(http.ErrAbortHandler)
+}
+
+type streamWriter struct {
+	ctx        *Context
+	format     StreamFormat
+	controller *http.ResponseController
+	csv        *csv.Writer
+	started    bool
+	count      int
+}
+
+func newStreamWriter(ctx *Context, format StreamFormat) (*streamWriter, *Error) {
+	w := &streamWriter{
+		ctx:        ctx,
+		format:     format,
+		controller: http.NewResponseController(ctx.ResponseWriter),
+	}
+	switch format {
+	case NDJSON:
+	case JSONArray:
+	case CSV:
+		w.csv = csv.NewWriter(ctx.ResponseWriter)
+	default:
+		return nil, NewError("Unknown stream format").SetCode(http.StatusInternalServerError)
+	}
+
+	return w, nil
+}
+
+func (w *streamWriter) start() {
+	if w.started {
+		return
+	}
+	w.started = true
+
+	switch w.format {
+	case NDJSON:
+		w.ctx.Header("Content-Type", "application/x-ndjson")
+	case JSONArray:
+		w.ctx.Header("Content-Type", "application/json")
+	case CSV:
+		w.ctx.Header("Content-Type", "text/csv; charset=utf-8")
+	}
+	w.ctx.Header("X-Content-Type-Options", "nosniff")
+	w.ctx.Status(http.StatusOK)
+}
+
+func (w *streamWriter) write(item any) error {
+	var chunk []byte
+
+	switch w.format {
+	case NDJSON, JSONArray:
+		encoded, err := json.Marshal(item)
+		if err != nil {
+			return err
+		}
+		if w.format == JSONArray {
+			if w.count == 0 {
+				chunk = append(chunk, '[')
+			} else {
+				chunk = append(chunk, ',')
+			}
+		}
+		chunk = append(chunk, encoded...)
+		if w.format == NDJSON {
+			chunk = append(chunk, '\n')
+		}
+	case CSV:
+		var record []string
+		switch row := item.(type) {
+		case []string:
+			record = row
+		case CSVRecord:
+			record = row.CSVRecord()
+		default:
+			return fmt.Errorf("%T can't be written as CSV", item)
+		}
+		w.start()
+		if err := w.csv.Write(record); err != nil {
+			return err
+		}
+		w.csv.Flush()
+		w.count++
+		return w.flush(w.csv.Error())
+	}
+
+	w.start()
+	_, err := w.ctx.Write(chunk)
+	w.count++
+	return w.flush(err)
+}
+
+func (w *streamWriter) close() error {
+	w.start()
+	if w.format == JSONArray {
+		closing := "]"
+		if w.count == 0 {
+			closing = "[]"
+		}
+		_, err := w.ctx.Write([]byte(closing))
+		return w.flush(err)
+	}
+	return w.flush(nil)
+}
+
+func (w *streamWriter) flush(err error) error {
+	if err != nil {
+		return err
+	}
+	if err := w.controller.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	return nil
+}
+
+// decodes the request body one element at a time
+// the body can be a json array or newline delimited json
+func DecodeStream[T any](ctx *Context) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+
+		reader := bufio.NewReader(ctx.Request.Body)
+		first, err := peekNonSpace(reader)
+		if errors.Is(err, io.EOF) {
+			return
+		}
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+
+		decoder := json.NewDecoder(reader)
+		isArray := first == '['
+		if isArray {
+			if _, err := decoder.Token(); err != nil {
+				yield(zero, err)
+				return
+			}
+		}
+
+		for {
+			if isArray && !decoder.More() {
+				if _, err := decoder.Token(); err != nil {
+					yield(zero, err)
+				}
+				return
+			}
+
+			var item T
+			if err := decoder.Decode(&item); err != nil {
+				if !isArray && errors.Is(err, io.EOF) {
+					return
+				}
+				yield(zero, err)
+				return
+			}
+			if !yield(item, nil) {
+				return
+			}
+		}
+	}
+}
+
+func peekNonSpace(reader *bufio.Reader) (byte, error) {
+	for {
+		b, err := reader.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		if !bytes.ContainsRune([]byte(" \t\r\n"), rune(b)) {
+			return b, reader.UnreadByte()
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package plaud
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"iter"
+	"net/http"
+	"net/http/httptest"
+	"plaudern/utils"
+	"slices"
+	"testing"
+)
+
+type streamRecord struct {
+	ID int `json:"id"`
+}
+
+func (r streamRecord) CSVRecord() []string {
+	return []string{"record", string(rune('0' + r.ID))}
+}
+
+func TestStream(t *testing.T) {
+	records := []streamRecord{{ID: 1}, {ID: 2}}
+
+	server := New(":8000")
+	testRouter := NewRouter("/")
+	testRouter.Get("/ndjson", func(ctx *Context) (*Data, *Error) {
+		return nil, Stream(ctx, NDJSON, slices.Values(records))
+	})
+	testRouter.Get("/array", func(ctx *Context) (*Data, *Error) {
+		return nil, Stream(ctx, JSONArray, slices.Values(records))
+	})
+	testRouter.Get("/csv", func(ctx *Context) (*Data, *Error) {
+		return nil, Stream(ctx, CSV, slices.Values(records))
+	})
+	testRouter.Get("/fail", func(ctx *Context) (*Data, *Error) {
+		var seq iter.Seq2[streamRecord, error] = func(yield func(streamRecord, error) bool) {
+			yield(streamRecord{}, errors.New("database down"))
+		}
+		return nil, Stream2(ctx, JSONArray, seq)
+	})
+	server.Register(testRouter)
+
+	tests := []struct {
+		path        string
+		code        int
+		contentType string
+		body        string
+	}{
+		{"/ndjson", http.StatusOK, "application/x-ndjson", "{\"id\":1}\n{\"id\":2}\n"},
+		{"/array", http.StatusOK, "application/json", `[{"id":1},{"id":2}]`},
+		{"/csv", http.StatusOK, "text/csv; charset=utf-8", "record,1\nrecord,2\n"},
+		{"/fail", http.StatusInternalServerError, "application/json", ""},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodGet, test.path, http.NoBody)
+		res := httptest.NewRecorder()
+		server.server.ServeHTTP(res, req)
+
+		utils.AssertEq(t, test.code, res.Code)
+		utils.AssertEq(t, test.contentType, res.Header().Get("Content-Type"))
+		if test.body != "" {
+			utils.AssertEq(t, test.body, res.Body.String())
+			continue
+		}
+
+		// the cause is not sent to the client
+		streamErr := &Error{}
+		utils.AssertNoErr(t, json.NewDecoder(res.Body).Decode(streamErr))
+		utils.AssertEq(t, "Internal Server Error", streamErr.Message)
+	}
+}
+
+func TestStreamAbort(t *testing.T) {
+	server := New(":8000")
+	testRouter := NewRouter("/")
+	testRouter.Get("/array", func(ctx *Context) (*Data, *Error) {
+		var seq iter.Seq2[streamRecord, error] = func(yield func(streamRecord, error) bool) {
+			if yield(streamRecord{ID: 1}, nil) {
+				yield(streamRecord{}, errors.New("database down"))
+			}
+		}
+		return nil, Stream2(ctx, JSONArray, seq)
+	})
+	server.Register(testRouter)
+
+	httpServer := httptest.NewServer(server.server)
+	defer httpServer.Close()
+
+	res, err := http.Get(httpServer.URL + "/array")
+	utils.AssertNoErr(t, err)
+	defer res.Body.Close()
+	utils.AssertEq(t, http.StatusOK, res.StatusCode)
+
+	// the array is never terminated, reading the body fails
+	body, err := io.ReadAll(res.Body)
+	utils.AssertNoEq(t, nil, err)
+	utils.AssertEq(t, `[{"id":1}`, string(body))
+}
+
+func TestDecodeStream(t *testing.T) {
+	bodies := []string{
+		` [{"id":1},{"id":2},{"id":3}]`,
+		"{\"id\":1}\n{\"id\":2}\n{\"id\":3}\n",
+	}
+
+	for _, body := range bodies {
+		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(body))
+		ctx := NewContext(httptest.NewRecorder(), req)
+
+		var ids []int
+		for record, err := range DecodeStream[streamRecord](ctx) {
+			utils.AssertNoErr(t, err)
+			ids = append(ids, record.ID)
+		}
+		if !slices.Equal(ids, []int{1, 2, 3}) {
+			t.Fatalf("Expected: %v Got: %v", []int{1, 2, 3}, ids)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(`[{"id":1},{"id":`))
+	ctx := NewContext(httptest.NewRecorder(), req)
+	var failed bool
+	for _, err := range DecodeStream[streamRecord](ctx) {
+		failed = err != nil
+	}
+	if !failed {
+		t.Fatal("Expected truncated body to fail decoding")
+	}
+}
@@ -0,0 +1,488 @@
+package plaud
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const TusVersion = "1.0.0"
+
+var (
+	ErrUploadNotFound = errors.New("upload not found")
+	ErrOffsetMismatch = errors.New("upload offset mismatch")
+)
+
+// state of a resumable upload
+type TusInfo struct {
+	ID       string            `json:"id"`
+	Length   int64             `json:"length"`
+	Offset   int64             `json:"offset"`
+	Metadata map[string]string `json:"metadata"`
+	// zero when the upload never expires
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func (info TusInfo) Complete() bool {
+	return info.Offset == info.Length
+}
+
+// storage backend of resumable uploads
+type TusStore interface {
+	// stores a new upload, the ID is set by the store
+	Create(TusInfo) (TusInfo, error)
+	Info(id string) (TusInfo, error)
+	// appends to the upload at offset, returns the new offset
+	Append(id string, offset int64, r io.Reader) (int64, error)
+	Terminate(id string) error
+}
+
+type TusOptions struct {
+	// largest accepted upload in bytes, 0 means unlimited
+	MaxSize int64
+	// incomplete uploads expire after this duration, 0 disables expiration
+	Expiration time.Duration
+	// called once an upload received all its bytes
+	OnComplete func(*Context, TusInfo)
+}
+
+// routes sharing the same middlewares
+type RouteGroup []HTTPRoute
+
+// registers the middlewares on every route of the group
+func (g RouteGroup) Use(middlewares ...MiddleWareFunc) RouteGroup {
+	for _, route := range g {
+		if route != nil {
+			route.Use(middlewares...)
+		}
+	}
+	return g
+}
+
+// registers a tus 1.0 resumable upload endpoint
+// supports the creation, termination and expiration extensions
+// POST path creates an upload, HEAD/PATCH/DELETE path/{id} query, append to and remove it
+func (r *Router) TusUpload(path string, store TusStore, opts TusOptions) RouteGroup {
+	tus := &tusHandler{store: store, opts: opts}
+	path = strings.TrimRight(path, "/")
+
+	return RouteGroup{
+		r.createRoute(OPTIONS, path, tus.options),
+		r.createRoute(POST, path, tus.create),
+		r.createRoute(HEAD, path+"/{id}", tus.head),
+		r.createRoute(PATCH, path+"/{id}", tus.patch),
+		r.createRoute(DELETE, path+"/{id}", tus.terminate),
+	}
+}
+
+type tusHandler struct {
+	store TusStore
+	opts  TusOptions
+
+	// unix nanoseconds of the last removal of expired uploads
+	lastSweep atomic.Int64
+}
+
+// implemented by stores able to remove their expired uploads
+type tusExpirer interface {
+	RemoveExpired(now time.Time) error
+}
+
+// removes the expired uploads of the store in the background
+// runs at most once per Expiration, triggered by the creation of uploads
+func (t *tusHandler) removeExpired() {
+	expirer, ok := t.store.(tusExpirer)
+	if !ok || t.opts.Expiration <= 0 {
+		return
+	}
+
+	now := time.Now()
+	last := t.lastSweep.Load()
+	if now.UnixNano()-last < int64(t.opts.Expiration) || !t.lastSweep.CompareAndSwap(last, now.UnixNano()) {
+		return
+	}
+	go func() {
+		if err := expirer.RemoveExpired(now); err != nil {
+			slog.Error("Failed to remove expired uploads", "error", err)
+		}
+	}()
+}
+
+func (t *tusHandler) options(ctx *Context) (*Data, *Error) {
+	ctx.Header("Tus-Resumable", TusVersion)
+	ctx.Header("Tus-Version", TusVersion)
+	extensions := "creation,termination"
+	if t.opts.Expiration > 0 {
+		extensions += ",expiration"
+	}
+	ctx.Header("Tus-Extension", extensions)
+	if t.opts.MaxSize > 0 {
+		ctx.Header("Tus-Max-Size", strconv.FormatInt(t.opts.MaxSize, 10))
+	}
+	ctx.Status(http.StatusNoContent)
+	return nil, nil
+}
+
+func (t *tusHandler) checkVersion(ctx *Context) *Error {
+	ctx.Header("Tus-Resumable", TusVersion)
+	if ctx.Request.Header.Get("Tus-Resumable") != TusVersion {
+		ctx.Header("Tus-Version", TusVersion)
+		return NewError("Unsupported tus version").SetCode(http.StatusPreconditionFailed)
+	}
+	return nil
+}
+
+func (t *tusHandler) create(ctx *Context) (*Data, *Error) {
+	if err := t.checkVersion(ctx); err != nil {
+		return nil, err
+	}
+
+	length, err := strconv.ParseInt(ctx.Request.Header.Get("Upload-Length"), 10, 64)
+	if err != nil || length < 0 {
+		return nil, NewError("Invalid Upload-Length").SetCode(http.StatusBadRequest)
+	}
+	if t.opts.MaxSize > 0 && length > t.opts.MaxSize {
+		return nil, NewError("Upload too large").SetCode(http.StatusRequestEntityTooLarge)
+	}
+	metadata, err := parseTusMetadata(ctx.Request.Header.Get("Upload-Metadata"))
+	if err != nil {
+		return nil, NewError("Invalid Upload-Metadata").SetCode(http.StatusBadRequest)
+	}
+
+	info := TusInfo{Length: length, Metadata: metadata}
+	if t.opts.Expiration > 0 {
+		info.ExpiresAt = time.Now().Add(t.opts.Expiration).UTC()
+	}
+	info, err = t.store.Create(info)
+	if err != nil {
+		return nil, NewError("Failed to create upload").SetCode(http.StatusInternalServerError)
+	}
+	t.removeExpired()
+
+	ctx.Header("Location", strings.TrimRight(ctx.Request.URL.Path, "/")+"/"+info.ID)
+	t.setExpires(ctx, info)
+	ctx.Status(http.StatusCreated)
+	if info.Complete() {
+		t.complete(ctx, info)
+	}
+	return nil, nil
+}
+
+// returns the upload, removing it once expired
+func (t *tusHandler) lookup(ctx *Context) (TusInfo, *Error) {
+	info, err := t.store.Info(ctx.Request.PathValue("id"))
+	if err != nil {
+		return info, NewError("Upload not found").SetCode(http.StatusNotFound)
+	}
+	if !info.ExpiresAt.IsZero() && !info.Complete() && time.Now().After(info.ExpiresAt) {
+		_ = t.store.Terminate(info.ID)
+		return info, NewError("Upload expired").SetCode(http.StatusGone)
+	}
+	return info, nil
+}
+
+func (t *tusHandler) head(ctx *Context) (*Data, *Error) {
+	if err := t.checkVersion(ctx); err != nil {
+		return nil, err
+	}
+	info, err := t.lookup(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx.Header("Upload-Offset", strconv.FormatInt(info.Offset, 10))
+	ctx.Header("Upload-Length", strconv.FormatInt(info.Length, 10))
+	if len(info.Metadata) > 0 {
+		ctx.Header("Upload-Metadata", formatTusMetadata(info.Metadata))
+	}
+	ctx.Header("Cache-Control", "no-store")
+	t.setExpires(ctx, info)
+	ctx.Status(http.StatusOK)
+	return nil, nil
+}
+
+func (t *tusHandler) patch(ctx *Context) (*Data, *Error) {
+	if err := t.checkVersion(ctx); err != nil {
+		return nil, err
+	}
+	if ctx.Request.Header.Get("Content-Type") != "application/offset+octet-stream" {
+		return nil, NewError("Invalid Content-Type").SetCode(http.StatusUnsupportedMediaType)
+	}
+	offset, parseErr := strconv.ParseInt(ctx.Request.Header.Get("Upload-Offset"), 10, 64)
+	if parseErr != nil || offset < 0 {
+		return nil, NewError("Invalid Upload-Offset").SetCode(http.StatusBadRequest)
+	}
+
+	info, err := t.lookup(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if offset != info.Offset {
+		return nil, NewError("Upload-Offset mismatch").SetCode(http.StatusConflict)
+	}
+
+	// bytes past the declared length are ignored
+	body := io.LimitReader(ctx.Request.Body, info.Length-info.Offset)
+	newOffset, appendErr := t.store.Append(info.ID, offset, body)
+	if errors.Is(appendErr, ErrOffsetMismatch) {
+		return nil, NewError("Upload-Offset mismatch").SetCode(http.StatusConflict)
+	}
+	// a dropped connection still moves the offset, the client resumes from there
+	if appendErr != nil && newOffset == offset {
+		return nil, NewError("Failed to store upload").SetCode(http.StatusInternalServerError)
+	}
+	info.Offset = newOffset
+
+	ctx.Header("Upload-Offset", strconv.FormatInt(info.Offset, 10))
+	t.setExpires(ctx, info)
+	ctx.Status(http.StatusNoContent)
+	// only the patch that reached the length completes the upload
+	if newOffset > offset && info.Complete() {
+		t.complete(ctx, info)
+	}
+	return nil, nil
+}
+
+func (t *tusHandler) terminate(ctx *Context) (*Data, *Error) {
+	if err := t.checkVersion(ctx); err != nil {
+		return nil, err
+	}
+	if err := t.store.Terminate(ctx.Request.PathValue("id")); err != nil {
+		return nil, NewError("Upload not found").SetCode(http.StatusNotFound)
+	}
+	ctx.Status(http.StatusNoContent)
+	return nil, nil
+}
+
+func (t *tusHandler) setExpires(ctx *Context, info TusInfo) {
+	if !info.ExpiresAt.IsZero() && !info.Complete() {
+		ctx.Header("Upload-Expires", info.ExpiresAt.Format(http.TimeFormat))
+	}
+}
+
+func (t *tusHandler) complete(ctx *Context, info TusInfo) {
+	if t.opts.OnComplete != nil {
+		t.opts.OnComplete(ctx, info)
+	}
+}
+
+// key base64(value),key2 base64(value2)
+func parseTusMetadata(header string) (map[string]string, error) {
+	metadata := make(map[string]string)
+	if strings.TrimSpace(header) == "" {
+		return metadata, nil
+	}
+	for _, pair := range strings.Split(header, ",") {
+		key, encoded, _ := strings.Cut(strings.TrimSpace(pair), " ")
+		if key == "" {
+			return nil, errors.New("empty metadata key")
+		}
+		value, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil {
+			return nil, err
+		}
+		metadata[key] = string(value)
+	}
+	return metadata, nil
+}
+
+func formatTusMetadata(metadata map[string]string) string {
+	pairs := make([]string, 0, len(metadata))
+	for key, value := range metadata {
+		pairs = append(pairs, key+" "+base64.StdEncoding.EncodeToString([]byte(value)))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// stores uploads as files in a directory
+// <id> holds the content and <id>.info the state
+type FileTusStore struct {
+	dir string
+
+	// guards locks
+	mu sync.Mutex
+	// held while an upload is appended to or terminated
+	locks map[string]*uploadLock
+}
+
+type uploadLock struct {
+	sync.Mutex
+	refs int
+}
+
+func NewFileTusStore(dir string) (*FileTusStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileTusStore{dir: dir, locks: make(map[string]*uploadLock)}, nil
+}
+
+// locks a single upload, the returned function unlocks it
+func (s *FileTusStore) lock(id string) func() {
+	s.mu.Lock()
+	lock, ok := s.locks[id]
+	if !ok {
+		lock = &uploadLock{}
+		s.locks[id] = lock
+	}
+	lock.refs++
+	s.mu.Unlock()
+
+	lock.Lock()
+	return func() {
+		lock.Unlock()
+		s.mu.Lock()
+		if lock.refs--; lock.refs == 0 {
+			delete(s.locks, id)
+		}
+		s.mu.Unlock()
+	}
+}
+
+// path of the content of the upload
+func (s *FileTusStore) Path(id string) string {
+	return filepath.Join(s.dir, id)
+}
+
+func (s *FileTusStore) infoPath(id string) string {
+	return filepath.Join(s.dir, id+".info")
+}
+
+func (s *FileTusStore) Create(info TusInfo) (TusInfo, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return info, err
+	}
+	info.ID = hex.EncodeToString(id)
+	info.Offset = 0
+
+	file, err := os.OpenFile(s.Path(info.ID), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if err != nil {
+		return info, err
+	}
+	file.Close()
+
+	if err := s.writeInfo(info); err != nil {
+		_ = os.Remove(s.Path(info.ID))
+		return info, err
+	}
+	return info, nil
+}
+
+// the state is replaced atomically by writeInfo, reading it needs no lock
+func (s *FileTusStore) Info(id string) (TusInfo, error) {
+	return s.readInfo(id)
+}
+
+// only the appended upload is locked, the others are served meanwhile
+func (s *FileTusStore) Append(id string, offset int64, r io.Reader) (int64, error) {
+	unlock := s.lock(id)
+	defer unlock()
+
+	info, err := s.readInfo(id)
+	if err != nil {
+		return offset, err
+	}
+	if info.Offset != offset {
+		return info.Offset, ErrOffsetMismatch
+	}
+
+	file, err := os.OpenFile(s.Path(id), os.O_WRONLY, 0o644)
+	if err != nil {
+		return offset, err
+	}
+	written, copyErr := io.Copy(io.NewOffsetWriter(file, offset), r)
+	if err := file.Close(); copyErr == nil {
+		copyErr = err
+	}
+
+	info.Offset += written
+	if err := s.writeInfo(info); err != nil {
+		return offset, err
+	}
+	return info.Offset, copyErr
+}
+
+func (s *FileTusStore) Terminate(id string) error {
+	unlock := s.lock(id)
+	defer unlock()
+
+	if _, err := s.readInfo(id); err != nil {
+		return err
+	}
+	if err := os.Remove(s.infoPath(id)); err != nil {
+		return err
+	}
+	return os.Remove(s.Path(id))
+}
+
+// removes incomplete uploads that expired before now
+// TusUpload runs it in the background as new uploads are created,
+// stores used without it must call it themselves
+func (s *FileTusStore) RemoveExpired(now time.Time) error {
+	infos, err := filepath.Glob(filepath.Join(s.dir, "*.info"))
+	if err != nil {
+		return err
+	}
+	for _, infoPath := range infos {
+		id := strings.TrimSuffix(filepath.Base(infoPath), ".info")
+		info, err := s.Info(id)
+		if err != nil {
+			continue
+		}
+		if !info.ExpiresAt.IsZero() && !info.Complete() && now.After(info.ExpiresAt) {
+			if err := s.Terminate(id); err != nil {
+				slog.Error("Failed to remove expired upload", "id", id, "error", err)
+			}
+		}
+	}
+	return nil
+}
+
+func (s *FileTusStore) readInfo(id string) (TusInfo, error) {
+	var info TusInfo
+	if id == "" || strings.ContainsAny(id, `/\.`) {
+		return info, ErrUploadNotFound
+	}
+
+	data, err := os.ReadFile(s.infoPath(id))
+	if errors.Is(err, fs.ErrNotExist) {
+		return info, ErrUploadNotFound
+	}
+	if err != nil {
+		return info, err
+	}
+	if err := json.Unmarshal(data, &info); err != nil {
+		return info, fmt.Errorf("corrupted upload info %s: %w", id, err)
+	}
+	return info, nil
+}
+
+// written to a temporary file and renamed so a crash never leaves a partial state
+func (s *FileTusStore) writeInfo(info TusInfo) error {
+	data, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+	tmp := s.infoPath(info.ID) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.infoPath(info.ID))
+}
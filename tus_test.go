@@ -0,0 +1,163 @@
+package plaud
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"plaudern/utils"
+	"testing"
+	"time"
+)
+
+func TestTusUpload(t *testing.T) {
+	store, err := NewFileTusStore(t.TempDir())
+	utils.AssertNoErr(t, err)
+
+	var completed TusInfo
+	var completions int
+	server := New(":8000")
+	testRouter := NewRouter("/")
+	testRouter.TusUpload("/files", store, TusOptions{
+		MaxSize:    1024,
+		Expiration: time.Hour,
+		OnComplete: func(_ *Context, info TusInfo) {
+			completed = info
+			completions++
+		},
+	})
+	server.Register(testRouter)
+
+	do := func(method, path string, body []byte, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewReader(body))
+		req.Header.Set("Tus-Resumable", TusVersion)
+		for key, value := range headers {
+			req.Header.Set(key, value)
+		}
+		res := httptest.NewRecorder()
+		server.server.ServeHTTP(res, req)
+		return res
+	}
+	patchHeaders := func(offset string) map[string]string {
+		return map[string]string{"Content-Type": "application/offset+octet-stream", "Upload-Offset": offset}
+	}
+
+	res := do(http.MethodOptions, "/files", nil, nil)
+	utils.AssertEq(t, http.StatusNoContent, res.Code)
+	utils.AssertEq(t, "creation,termination,expiration", res.Header().Get("Tus-Extension"))
+
+	res = do(http.MethodPost, "/files", nil, map[string]string{"Upload-Length": "2048"})
+	utils.AssertEq(t, http.StatusRequestEntityTooLarge, res.Code)
+
+	// filename: video.mp4
+	res = do(http.MethodPost, "/files", nil, map[string]string{"Upload-Length": "11", "Upload-Metadata": "filename dmlkZW8ubXA0"})
+	utils.AssertEq(t, http.StatusCreated, res.Code)
+	location := res.Header().Get("Location")
+	utils.AssertNoEq(t, "", res.Header().Get("Upload-Expires"))
+
+	res = do(http.MethodPatch, location, []byte("hello "), patchHeaders("0"))
+	utils.AssertEq(t, http.StatusNoContent, res.Code)
+	utils.AssertEq(t, "6", res.Header().Get("Upload-Offset"))
+
+	res = do(http.MethodPatch, location, []byte("again"), patchHeaders("0"))
+	utils.AssertEq(t, http.StatusConflict, res.Code)
+
+	res = do(http.MethodHead, location, nil, nil)
+	utils.AssertEq(t, http.StatusOK, res.Code)
+	utils.AssertEq(t, "6", res.Header().Get("Upload-Offset"))
+	utils.AssertEq(t, "11", res.Header().Get("Upload-Length"))
+
+	res = do(http.MethodPatch, location, []byte("world"), patchHeaders("6"))
+	utils.AssertEq(t, http.StatusNoContent, res.Code)
+	utils.AssertEq(t, "video.mp4", completed.Metadata["filename"])
+
+	// an empty patch to a finished upload doesn't complete it again
+	res = do(http.MethodPatch, location, nil, patchHeaders("11"))
+	utils.AssertEq(t, http.StatusNoContent, res.Code)
+	utils.AssertEq(t, 1, completions)
+
+	content, err := os.ReadFile(store.Path(completed.ID))
+	utils.AssertNoErr(t, err)
+	utils.AssertEq(t, "hello world", string(content))
+
+	req := httptest.NewRequest(http.MethodHead, location, http.NoBody)
+	res = httptest.NewRecorder()
+	server.server.ServeHTTP(res, req)
+	utils.AssertEq(t, http.StatusPreconditionFailed, res.Code)
+
+	res = do(http.MethodDelete, location, nil, nil)
+	utils.AssertEq(t, http.StatusNoContent, res.Code)
+	res = do(http.MethodHead, location, nil, nil)
+	utils.AssertEq(t, http.StatusNotFound, res.Code)
+}
+
+func TestFileTusStoreConcurrentUploads(t *testing.T) {
+	store, err := NewFileTusStore(t.TempDir())
+	utils.AssertNoErr(t, err)
+
+	stalled, err := store.Create(TusInfo{Length: 10})
+	utils.AssertNoErr(t, err)
+	other, err := store.Create(TusInfo{Length: 5})
+	utils.AssertNoErr(t, err)
+
+	// a client that stopped sending keeps its own upload locked
+	reader, writer := io.Pipe()
+	stalledDone := make(chan struct{})
+	go func() {
+		_, _ = store.Append(stalled.ID, 0, reader)
+		close(stalledDone)
+	}()
+	defer func() {
+		writer.Close()
+		<-stalledDone
+	}()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := store.Append(other.ID, 0, bytes.NewReader([]byte("hello")))
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		utils.AssertNoErr(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("Append blocked by another upload")
+	}
+
+	info, err := store.Info(stalled.ID)
+	utils.AssertNoErr(t, err)
+	utils.AssertEq(t, int64(0), info.Offset)
+}
+
+func TestTusUploadRemovesExpired(t *testing.T) {
+	store, err := NewFileTusStore(t.TempDir())
+	utils.AssertNoErr(t, err)
+	expired, err := store.Create(TusInfo{Length: 10, ExpiresAt: time.Now().Add(-time.Minute)})
+	utils.AssertNoErr(t, err)
+
+	server := New(":8000")
+	testRouter := NewRouter("/")
+	testRouter.TusUpload("/files", store, TusOptions{Expiration: time.Hour})
+	server.Register(testRouter)
+
+	req := httptest.NewRequest(http.MethodPost, "/files", nil)
+	req.Header.Set("Tus-Resumable", TusVersion)
+	req.Header.Set("Upload-Length", "5")
+	res := httptest.NewRecorder()
+	server.server.ServeHTTP(res, req)
+	utils.AssertEq(t, http.StatusCreated, res.Code)
+
+	// removed in the background
+	deadline := time.Now().Add(time.Second)
+	for {
+		if _, err := os.Stat(store.Path(expired.ID)); errors.Is(err, os.ErrNotExist) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expired upload was not removed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
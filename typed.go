@@ -0,0 +1,187 @@
+package plaud
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+)
+
+// handler with a typed input and output
+// the input is bound from the request, the output is wrapped in Data
+// answered with 200, or the status passed to the helper, e.g. Post(r, "/", fn, http.StatusCreated)
+type TypedFunc[In, Out any] func(*Context, In) (Out, *Error)
+
+// implemented by inputs that check themselves after binding
+// a returned error answers 422 with its message
+type Validator interface {
+	Validate() error
+}
+
+func Get[In, Out any](router HTTPRouter, path string, fn TypedFunc[In, Out], status ...int) HTTPRoute {
+	code := typedStatus(status)
+	return typedRoute(router.Get(path, bindTyped(fn, false, code)), fn, false, code)
+}
+
+func Post[In, Out any](router HTTPRouter, path string, fn TypedFunc[In, Out], status ...int) HTTPRoute {
+	code := typedStatus(status)
+	return typedRoute(router.Post(path, bindTyped(fn, true, code)), fn, true, code)
+}
+
+func Put[In, Out any](router HTTPRouter, path string, fn TypedFunc[In, Out], status ...int) HTTPRoute {
+	code := typedStatus(status)
+	return typedRoute(router.Put(path, bindTyped(fn, true, code)), fn, true, code)
+}
+
+func Patch[In, Out any](router HTTPRouter, path string, fn TypedFunc[In, Out], status ...int) HTTPRoute {
+	code := typedStatus(status)
+	return typedRoute(router.Patch(path, bindTyped(fn, true, code)), fn, true, code)
+}
+
+func Delete[In, Out any](router HTTPRouter, path string, fn TypedFunc[In, Out], status ...int) HTTPRoute {
+	code := typedStatus(status)
+	return typedRoute(router.Delete(path, bindTyped(fn, false, code)), fn, false, code)
+}
+
+func typedStatus(status []int) int {
+	if len(status) > 0 && status[0] != 0 {
+		return status[0]
+	}
+	return http.StatusOK
+}
+
+// records the input and output types for the OpenAPI document
+func typedRoute[In, Out any](route HTTPRoute, fn TypedFunc[In, Out], hasBody bool, code int) HTTPRoute {
+	if route == nil {
+		return nil
+	}
+	if r, ok := route.(*Route); ok {
+		r.handlerName = funcName(fn)
+	}
+
+	inType := reflect.TypeFor[In]()
+	doc := RouteDoc{Response: reflect.TypeFor[Out](), Query: inType, Status: code}
+	if hasBody {
+		doc.Request = inType
+	}
+	return route.Describe(doc)
+}
+
+func bindTyped[In, Out any](fn TypedFunc[In, Out], hasBody bool, code int) HTTPFunc {
+	return func(ctx *Context) (*Data, *Error) {
+		var in In
+		if err := bindInput(ctx, &in, hasBody); err != nil {
+			return nil, err
+		}
+
+		out, err := fn(ctx, in)
+		if err != nil {
+			return nil, err
+		}
+		return NewData(http.StatusText(code)).SetCode(code).SetData(out), nil
+	}
+}
+
+// decodes the json body, then fills the fields tagged `path` and `query`
+func bindInput(ctx *Context, in any, hasBody bool) *Error {
+	if hasBody {
+		err := json.NewDecoder(ctx.Request.Body).Decode(in)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return NewError("Invalid JSON Format").SetCode(http.StatusBadRequest)
+		}
+	}
+
+	value := reflect.ValueOf(in).Elem()
+	if value.Kind() == reflect.Struct {
+		query := ctx.Request.URL.Query()
+		for _, field := range reflect.VisibleFields(value.Type()) {
+			if !field.IsExported() || field.Anonymous {
+				continue
+			}
+
+			var raw []string
+			if name := field.Tag.Get("path"); name != "" {
+				if pathValue := ctx.Request.PathValue(name); pathValue != "" {
+					raw = []string{pathValue}
+				}
+			} else if name := field.Tag.Get("query"); name != "" {
+				raw = query[name]
+			}
+			if len(raw) == 0 {
+				continue
+			}
+
+			if err := setField(value.FieldByIndex(field.Index), raw); err != nil {
+				return NewError(fmt.Sprintf("Invalid value for %s", field.Name)).SetCode(http.StatusBadRequest)
+			}
+		}
+	}
+
+	// in is a pointer, so both value and pointer receivers match
+	if validator, ok := in.(Validator); ok {
+		if err := validator.Validate(); err != nil {
+			return NewError(err.Error()).SetCode(http.StatusUnprocessableEntity)
+		}
+	}
+	return nil
+}
+
+func setField(field reflect.Value, raw []string) error {
+	if field.Kind() == reflect.Slice && field.Type().Elem().Kind() != reflect.Uint8 {
+		slice := reflect.MakeSlice(field.Type(), len(raw), len(raw))
+		for i, value := range raw {
+			if err := setScalar(slice.Index(i), value); err != nil {
+				return err
+			}
+		}
+		field.Set(slice)
+		return nil
+	}
+	return setScalar(field, raw[0])
+}
+
+func setScalar(field reflect.Value, raw string) error {
+	if field.Kind() == reflect.Pointer {
+		ptr := reflect.New(field.Type().Elem())
+		if err := setScalar(ptr.Elem(), raw); err != nil {
+			return err
+		}
+		field.Set(ptr)
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(raw, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(raw, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(raw, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported field type %s", field.Type())
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package plaud
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"plaudern/utils"
+	"reflect"
+	"testing"
+)
+
+type createUser struct {
+	Name string `json:"name"`
+	Team int    `json:"-" path:"team"`
+	Dry  bool   `json:"-" query:"dry"`
+}
+
+func (c createUser) Validate() error {
+	if c.Name == "" {
+		return errors.New("name is required")
+	}
+	return nil
+}
+
+type typedUser struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	Team int    `json:"team"`
+}
+
+type userQuery struct {
+	IDs []int `query:"id"`
+}
+
+func createTypedUser(_ *Context, in createUser) (typedUser, *Error) {
+	if in.Dry {
+		return typedUser{}, NewError("dry run").SetCode(http.StatusAccepted)
+	}
+	return typedUser{ID: 1, Name: in.Name, Team: in.Team}, nil
+}
+
+func TestTypedHandlers(t *testing.T) {
+	server := New(":8000")
+	testRouter := NewRouter("/teams")
+	route := Post(testRouter, "/{team}/users", createTypedUser, http.StatusCreated).Describe(RouteDoc{Summary: "Create a user"})
+	Get(testRouter, "/users", func(_ *Context, in userQuery) ([]typedUser, *Error) {
+		users := make([]typedUser, 0, len(in.IDs))
+		for _, id := range in.IDs {
+			users = append(users, typedUser{ID: id})
+		}
+		return users, nil
+	})
+	server.Register(testRouter)
+
+	info := route.Info()
+	utils.AssertEq(t, "plaudern.createTypedUser", info.Handler)
+	utils.AssertEq(t, reflect.TypeFor[createUser](), info.Doc.Request.(reflect.Type))
+	utils.AssertEq(t, reflect.TypeFor[typedUser](), info.Doc.Response.(reflect.Type))
+	utils.AssertEq(t, http.StatusCreated, info.Doc.Status)
+	utils.AssertEq(t, "Create a user", info.Doc.Summary)
+
+	req := httptest.NewRequest(http.MethodPost, "/teams/7/users", bytes.NewBufferString(`{"name":"Jotaro"}`))
+	res := httptest.NewRecorder()
+	server.server.ServeHTTP(res, req)
+	utils.AssertEq(t, http.StatusCreated, res.Code)
+
+	var created struct {
+		Data typedUser `json:"data"`
+	}
+	utils.AssertNoErr(t, json.NewDecoder(res.Body).Decode(&created))
+	utils.AssertEq(t, typedUser{ID: 1, Name: "Jotaro", Team: 7}, created.Data)
+
+	tests := []struct {
+		method string
+		path   string
+		body   string
+		code   int
+	}{
+		{http.MethodPost, "/teams/7/users", `{"name":""}`, http.StatusUnprocessableEntity},
+		{http.MethodPost, "/teams/7/users", `{"name":`, http.StatusBadRequest},
+		{http.MethodPost, "/teams/seven/users", `{"name":"Jotaro"}`, http.StatusBadRequest},
+		{http.MethodPost, "/teams/7/users?dry=true", `{"name":"Jotaro"}`, http.StatusAccepted},
+		{http.MethodGet, "/teams/users?id=1&id=2", "", http.StatusOK},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(test.method, test.path, bytes.NewBufferString(test.body))
+		res := httptest.NewRecorder()
+		server.server.ServeHTTP(res, req)
+		if res.Code != test.code {
+			t.Fatalf("%s %s Expected: %v Got: %v", test.method, test.path, test.code, res.Code)
+		}
+	}
+}
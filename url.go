@@ -0,0 +1,112 @@
+package plaud
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// builds the path of the named route
+// params are key value pairs filling the wildcards of the pattern,
+// the ones left over are added as query parameters
+// for file handlers the "path" param is appended to the prefix
+//
+//	server.URL("user.show", "id", "42", "tab", "posts") // /users/42?tab=posts
+func (s *Server) URL(name string, params ...string) (string, error) {
+	if len(params)%2 != 0 {
+		return "", fmt.Errorf("url %s: odd number of params", name)
+	}
+
+	info, ok := s.named[name]
+	if !ok {
+		return "", fmt.Errorf("url %s: no route with that name", name)
+	}
+	return buildURL(info, params)
+}
+
+func buildURL(info RouteInfo, params []string) (string, error) {
+	values := make(map[string]string, len(params)/2)
+	for i := 0; i < len(params); i += 2 {
+		values[params[i]] = params[i+1]
+	}
+
+	segments := strings.Split(info.Pattern, "/")
+	for i, segment := range segments {
+		if !strings.HasPrefix(segment, "{") || !strings.HasSuffix(segment, "}") {
+			continue
+		}
+
+		wildcard := strings.TrimSuffix(strings.TrimPrefix(segment, "{"), "}")
+		if wildcard == "$" {
+			segments[i] = ""
+			continue
+		}
+
+		key, rest := strings.CutSuffix(wildcard, "...")
+		value, ok := values[key]
+		if !ok {
+			return "", fmt.Errorf("url %s: missing param %q", info.Name, key)
+		}
+		delete(values, key)
+
+		if rest {
+			parts := strings.Split(strings.TrimLeft(value, "/"), "/")
+			for j, part := range parts {
+				parts[j] = url.PathEscape(part)
+			}
+			segments[i] = strings.Join(parts, "/")
+		} else {
+			if value == "" {
+				return "", fmt.Errorf("url %s: empty param %q", info.Name, key)
+			}
+			segments[i] = url.PathEscape(value)
+		}
+	}
+	path := strings.Join(segments, "/")
+
+	if value, ok := values["path"]; ok && info.Method == "" {
+		delete(values, "path")
+		parts := strings.Split(strings.TrimLeft(value, "/"), "/")
+		for j, part := range parts {
+			parts[j] = url.PathEscape(part)
+		}
+		path = strings.TrimRight(path, "/") + "/" + strings.Join(parts, "/")
+	}
+
+	if len(values) > 0 {
+		query := make(url.Values, len(values))
+		for key, value := range values {
+			query.Set(key, value)
+		}
+		path += "?" + query.Encode()
+	}
+	return path, nil
+}
+
+// builds the path of the named route of the server handling the request, see Server.URL
+func (c *Context) URLFor(name string, params ...string) (string, *Error) {
+	if c.server == nil {
+		return "", NewError("No server to resolve routes").SetCode(http.StatusInternalServerError)
+	}
+	path, err := c.server.URL(name, params...)
+	if err != nil {
+		return "", NewError(err.Error()).SetCode(http.StatusInternalServerError)
+	}
+	return path, nil
+}
+
+func (c *Context) Redirect(code int, location string) {
+	http.Redirect(c.ResponseWriter, c.Request, location, code)
+	c.Abort()
+}
+
+// redirects to the named route with 302 Found
+func (c *Context) RedirectTo(name string, params ...string) *Error {
+	location, err := c.URLFor(name, params...)
+	if err != nil {
+		return err
+	}
+	c.Redirect(http.StatusFound, location)
+	return nil
+}
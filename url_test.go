@@ -0,0 +1,74 @@
+package plaud
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"plaudern/utils"
+	"testing"
+)
+
+func TestNamedRoutes(t *testing.T) {
+	server := New(":8000")
+	apiRouter := NewRouter("/api")
+	usersRouter := NewRouter("/users")
+	usersRouter.Get("/{id}", func(_ *Context) (*Data, *Error) {
+		return NewData("user"), nil
+	}).Name("user.show")
+	usersRouter.Get("/{id}/files/{path...}", func(_ *Context) (*Data, *Error) {
+		return NewData("file"), nil
+	}).Name("user.file")
+	usersRouter.Get("/old/{id}", func(ctx *Context) (*Data, *Error) {
+		return nil, ctx.RedirectTo("user.show", "id", ctx.Request.PathValue("id"))
+	})
+	apiRouter.ServeDir("/static", http.Dir("./test_files")).Name("static")
+	apiRouter.Handle("/v1", usersRouter)
+	server.Register(apiRouter)
+
+	tests := []struct {
+		name     string
+		params   []string
+		expected string
+	}{
+		{"user.show", []string{"id", "42"}, "/api/v1/users/42"},
+		{"user.show", []string{"id", "a b/c", "tab", "posts"}, "/api/v1/users/a%20b%2Fc?tab=posts"},
+		{"user.file", []string{"id", "7", "path", "docs/read me.txt"}, "/api/v1/users/7/files/docs/read%20me.txt"},
+		{"static", []string{"path", "css/style.css"}, "/api/static/css/style.css"},
+	}
+	for _, test := range tests {
+		url, err := server.URL(test.name, test.params...)
+		utils.AssertNoErr(t, err)
+		utils.AssertEq(t, test.expected, url)
+	}
+
+	if _, err := server.URL("user.show"); err == nil {
+		t.Fatal("Expected missing param to fail")
+	}
+	if _, err := server.URL("user.delete", "id", "1"); err == nil {
+		t.Fatal("Expected unknown route to fail")
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/users/old/9", http.NoBody)
+	res := httptest.NewRecorder()
+	server.server.ServeHTTP(res, req)
+	utils.AssertEq(t, http.StatusFound, res.Code)
+	utils.AssertEq(t, "/api/v1/users/9", res.Header().Get("Location"))
+}
+
+func TestDuplicateRouteNames(t *testing.T) {
+	server := New(":8000")
+	usersRouter := NewRouter("/users")
+	usersRouter.Get("/{id}", listUsers).Name("show")
+	postsRouter := NewRouter("/posts")
+	postsRouter.Get("/{id}", listUsers).Name("show")
+	server.Register(usersRouter)
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Expected a duplicate route name to panic")
+		}
+		url, err := server.URL("show", "id", "1")
+		utils.AssertNoErr(t, err)
+		utils.AssertEq(t, "/users/1", url)
+	}()
+	server.Register(postsRouter)
+}
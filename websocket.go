@@ -0,0 +1,471 @@
+package plaud
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// websocket opcodes (RFC 6455 section 5.2)
+const (
+	continuationFrame = 0
+	TextMessage       = 1
+	BinaryMessage     = 2
+	CloseMessage      = 8
+	PingMessage       = 9
+	PongMessage       = 10
+)
+
+// websocket close codes (RFC 6455 section 7.4.1)
+const (
+	CloseNormalClosure   = 1000
+	CloseGoingAway       = 1001
+	CloseProtocolError   = 1002
+	CloseUnsupportedData = 1003
+	CloseNoStatus        = 1005
+	CloseInvalidPayload  = 1007
+	ClosePolicyViolation = 1008
+	CloseMessageTooBig   = 1009
+	CloseInternalError   = 1011
+)
+
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// largest message accepted from a client, fragments included
+var DefaultMaxMessageSize int64 = 1 << 20
+
+// longest a frame write may take before the connection is dropped
+// a client that stops reading can't block the writers, or Close, for longer
+var DefaultWriteTimeout = 10 * time.Second
+
+// the close frame is best effort, it gets a shorter deadline
+const closeWriteTimeout = time.Second
+
+// configures the websocket handshake
+type WebSocketOption func(*webSocketOptions)
+
+type webSocketOptions struct {
+	checkOrigin func(*http.Request) bool
+}
+
+// accepts handshakes from these origins next to the same origin
+// e.g. WithOrigins("https://app.example.com")
+func WithOrigins(origins ...string) WebSocketOption {
+	return func(o *webSocketOptions) {
+		o.checkOrigin = func(r *http.Request) bool {
+			origin := r.Header.Get("Origin")
+			return sameOrigin(r) || slices.ContainsFunc(origins, func(allowed string) bool {
+				return strings.EqualFold(allowed, origin)
+			})
+		}
+	}
+}
+
+// replaces the same origin check, a check returning true turns it off
+func WithCheckOrigin(check func(*http.Request) bool) WebSocketOption {
+	return func(o *webSocketOptions) {
+		o.checkOrigin = check
+	}
+}
+
+// browsers send cookies with cross-site websocket handshakes,
+// so handshakes from other origins are refused by default
+// clients sending no Origin, like non-browser ones, are accepted
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, r.Host)
+}
+
+// type of the function that handles an upgraded websocket connection
+// the connection is closed once the function returns
+type WebSocketFunc func(*Context, *WebSocketConn) error
+
+// returned by ReadMessage once the connection is closed
+type CloseError struct {
+	Code   int
+	Reason string
+}
+
+func (e *CloseError) Error() string {
+	return fmt.Sprintf("websocket closed: %d %s", e.Code, e.Reason)
+}
+
+// a server side websocket connection
+// reads must happen from a single goroutine, writes are safe to be used concurrently
+type WebSocketConn struct {
+	conn           net.Conn
+	reader         *bufio.Reader
+	maxMessageSize int64
+	writeTimeout   time.Duration
+
+	writeMu sync.Mutex
+	closed  bool
+	done    chan struct{}
+}
+
+// performs the websocket handshake and takes over the connection
+// handshakes from other origins answer 403, see WithOrigins
+func (c *Context) UpgradeWebSocket(opts ...WebSocketOption) (*WebSocketConn, *Error) {
+	options := webSocketOptions{checkOrigin: sameOrigin}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	r := c.Request
+	if r.Method != http.MethodGet {
+		return nil, NewError("WebSocket upgrade requires GET").SetCode(http.StatusMethodNotAllowed)
+	}
+	if !headerContainsToken(r.Header, "Connection", "upgrade") || !headerContainsToken(r.Header, "Upgrade", "websocket") {
+		return nil, NewError("Not a WebSocket handshake").SetCode(http.StatusBadRequest)
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		c.Header("Sec-WebSocket-Version", "13")
+		return nil, NewError("Unsupported WebSocket version").SetCode(http.StatusUpgradeRequired)
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 16 {
+		return nil, NewError("Invalid Sec-WebSocket-Key").SetCode(http.StatusBadRequest)
+	}
+	if !options.checkOrigin(r) {
+		return nil, NewError("Origin not allowed").SetCode(http.StatusForbidden)
+	}
+
+	conn, rw, err := http.NewResponseController(c.ResponseWriter).Hijack()
+	if err != nil {
+		return nil, NewError("WebSocket upgrade not supported").SetCode(http.StatusInternalServerError)
+	}
+
+	hash := sha1.Sum([]byte(key + websocketGUID))
+	response := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(hash[:]) + "\r\n\r\n"
+	if _, err := rw.WriteString(response); err != nil {
+		conn.Close()
+		return nil, NewError("WebSocket handshake failed").SetCode(http.StatusInternalServerError)
+	}
+	if err := rw.Flush(); err != nil {
+		conn.Close()
+		return nil, NewError("WebSocket handshake failed").SetCode(http.StatusInternalServerError)
+	}
+
+	return &WebSocketConn{
+		conn:           conn,
+		reader:         rw.Reader,
+		maxMessageSize: DefaultMaxMessageSize,
+		writeTimeout:   DefaultWriteTimeout,
+		done:           make(chan struct{}),
+	}, nil
+}
+
+func headerContainsToken(header http.Header, key, token string) bool {
+	for _, value := range header.Values(key) {
+		for _, part := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// sets the largest message accepted from the client
+func (ws *WebSocketConn) SetMaxMessageSize(size int64) {
+	ws.maxMessageSize = size
+}
+
+// sets the longest a frame write may take, 0 disables the deadline
+func (ws *WebSocketConn) SetWriteTimeout(timeout time.Duration) {
+	ws.writeMu.Lock()
+	defer ws.writeMu.Unlock()
+	ws.writeTimeout = timeout
+}
+
+// closed once the connection is closed
+func (ws *WebSocketConn) Done() <-chan struct{} {
+	return ws.done
+}
+
+// the remote address of the client
+func (ws *WebSocketConn) RemoteAddr() net.Addr {
+	return ws.conn.RemoteAddr()
+}
+
+// reads the next complete data message, reassembling fragments
+// pings are answered and pongs are skipped
+// returns a *CloseError once the connection is closed
+func (ws *WebSocketConn) ReadMessage() (int, []byte, error) {
+	var (
+		messageType int
+		message     []byte
+	)
+
+	for {
+		fin, opcode, payload, err := ws.readFrame()
+		if err != nil {
+			return 0, nil, ws.fail(err)
+		}
+
+		switch opcode {
+		case PingMessage:
+			if err := ws.writeFrame(PongMessage, payload); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case PongMessage:
+			continue
+		case CloseMessage:
+			return 0, nil, ws.closeReceived(payload)
+		case TextMessage, BinaryMessage:
+			if messageType != 0 {
+				return 0, nil, ws.fail(&CloseError{Code: CloseProtocolError, Reason: "expected continuation frame"})
+			}
+			messageType = opcode
+		case continuationFrame:
+			if messageType == 0 {
+				return 0, nil, ws.fail(&CloseError{Code: CloseProtocolError, Reason: "unexpected continuation frame"})
+			}
+		default:
+			return 0, nil, ws.fail(&CloseError{Code: CloseProtocolError, Reason: "unknown opcode"})
+		}
+
+		if int64(len(message)+len(payload)) > ws.maxMessageSize {
+			return 0, nil, ws.fail(&CloseError{Code: CloseMessageTooBig, Reason: "message too big"})
+		}
+		message = append(message, payload...)
+
+		if fin {
+			if messageType == TextMessage && !utf8.Valid(message) {
+				return 0, nil, ws.fail(&CloseError{Code: CloseInvalidPayload, Reason: "invalid utf-8"})
+			}
+			return messageType, message, nil
+		}
+	}
+}
+
+// writes a single unfragmented text or binary message
+func (ws *WebSocketConn) WriteMessage(messageType int, data []byte) error {
+	if messageType != TextMessage && messageType != BinaryMessage {
+		return errors.New("websocket: invalid message type")
+	}
+	return ws.writeFrame(messageType, data)
+}
+
+func (ws *WebSocketConn) WriteText(text string) error {
+	return ws.WriteMessage(TextMessage, []byte(text))
+}
+
+func (ws *WebSocketConn) Ping(data []byte) error {
+	return ws.writeFrame(PingMessage, data)
+}
+
+// sends a close frame and closes the underlying connection
+func (ws *WebSocketConn) Close(code int, reason string) error {
+	payload := make([]byte, 2, 2+len(reason))
+	binary.BigEndian.PutUint16(payload, uint16(code))
+	payload = append(payload, reason...)
+	if len(payload) > 125 {
+		payload = payload[:125]
+	}
+
+	ws.writeMu.Lock()
+	defer ws.writeMu.Unlock()
+	if ws.closed {
+		return nil
+	}
+	err := ws.writeFrameLocked(CloseMessage, payload)
+	if closeErr := ws.closeLocked(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// closes the underlying connection without a close frame
+func (ws *WebSocketConn) closeLocked() error {
+	if ws.closed {
+		return nil
+	}
+	ws.closed = true
+	close(ws.done)
+	return ws.conn.Close()
+}
+
+func (ws *WebSocketConn) closeReceived(payload []byte) error {
+	closeErr := &CloseError{Code: CloseNoStatus}
+	if len(payload) >= 2 {
+		closeErr.Code = int(binary.BigEndian.Uint16(payload))
+		closeErr.Reason = string(payload[2:])
+	}
+
+	code := closeErr.Code
+	if code == CloseNoStatus {
+		code = CloseNormalClosure
+	}
+	_ = ws.Close(code, "")
+	return closeErr
+}
+
+// closes the connection with the code of a protocol error
+func (ws *WebSocketConn) fail(err error) error {
+	var closeErr *CloseError
+	if errors.As(err, &closeErr) {
+		_ = ws.Close(closeErr.Code, closeErr.Reason)
+		return closeErr
+	}
+
+	ws.writeMu.Lock()
+	_ = ws.closeLocked()
+	ws.writeMu.Unlock()
+	if errors.Is(err, io.EOF) || errors.Is(err, net.ErrClosed) {
+		return &CloseError{Code: CloseGoingAway, Reason: "connection closed"}
+	}
+	return err
+}
+
+func (ws *WebSocketConn) readFrame() (bool, int, []byte, error) {
+	var header [2]byte
+	if _, err := io.ReadFull(ws.reader, header[:]); err != nil {
+		return false, 0, nil, err
+	}
+
+	fin := header[0]&0x80 != 0
+	opcode := int(header[0] & 0x0f)
+	masked := header[1]&0x80 != 0
+	length := int64(header[1] & 0x7f)
+
+	if header[0]&0x70 != 0 {
+		return false, 0, nil, &CloseError{Code: CloseProtocolError, Reason: "reserved bits set"}
+	}
+	if !masked {
+		return false, 0, nil, &CloseError{Code: CloseProtocolError, Reason: "client frames must be masked"}
+	}
+	if opcode >= CloseMessage && (!fin || length > 125) {
+		return false, 0, nil, &CloseError{Code: CloseProtocolError, Reason: "invalid control frame"}
+	}
+
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(ws.reader, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = int64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(ws.reader, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = int64(binary.BigEndian.Uint64(ext[:]))
+	}
+	if length < 0 || length > ws.maxMessageSize {
+		return false, 0, nil, &CloseError{Code: CloseMessageTooBig, Reason: "message too big"}
+	}
+
+	var mask [4]byte
+	if _, err := io.ReadFull(ws.reader, mask[:]); err != nil {
+		return false, 0, nil, err
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(ws.reader, payload); err != nil {
+		return false, 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+
+	return fin, opcode, payload, nil
+}
+
+func (ws *WebSocketConn) writeFrame(opcode int, payload []byte) error {
+	ws.writeMu.Lock()
+	defer ws.writeMu.Unlock()
+	if ws.closed {
+		return &CloseError{Code: CloseNormalClosure, Reason: "connection closed"}
+	}
+	return ws.writeFrameLocked(opcode, payload)
+}
+
+// server frames are never masked
+// a failed write leaves a partial frame behind, the connection is closed
+func (ws *WebSocketConn) writeFrameLocked(opcode int, payload []byte) error {
+	timeout := ws.writeTimeout
+	if opcode == CloseMessage && (timeout <= 0 || timeout > closeWriteTimeout) {
+		timeout = closeWriteTimeout
+	}
+	if timeout > 0 {
+		_ = ws.conn.SetWriteDeadline(time.Now().Add(timeout))
+	}
+
+	header := make([]byte, 2, 10)
+	header[0] = 0x80 | byte(opcode)
+
+	length := len(payload)
+	switch {
+	case length <= 125:
+		header[1] = byte(length)
+	case length <= 0xffff:
+		header[1] = 126
+		header = binary.BigEndian.AppendUint16(header, uint16(length))
+	default:
+		header[1] = 127
+		header = binary.BigEndian.AppendUint64(header, uint64(length))
+	}
+
+	if _, err := ws.conn.Write(append(header, payload...)); err != nil {
+		_ = ws.closeLocked()
+		return err
+	}
+	return nil
+}
+
+// registers a GET route upgrading the request to a websocket
+// the router and route middlewares run before the upgrade
+func (r *Router) WebSocket(path string, wsFunc WebSocketFunc, opts ...WebSocketOption) HTTPRoute {
+	return r.createWrappedRoute(GET, path, wsFunc, func(ctx *Context) (*Data, *Error) {
+		ws, err := ctx.UpgradeWebSocket(opts...)
+		if err != nil {
+			return nil, err
+		}
+
+		// the request context is cancelled on server shutdown
+		go func() {
+			select {
+			case <-ctx.Request.Context().Done():
+				_ = ws.Close(CloseGoingAway, "server shutting down")
+			case <-ws.Done():
+			}
+		}()
+
+		if err := wsFunc(ctx, ws); err != nil {
+			var closeErr *CloseError
+			if !errors.As(err, &closeErr) {
+				slog.Error("WebSocket handler failed", "path", ctx.Request.URL.Path, "error", err)
+				_ = ws.Close(CloseInternalError, "")
+				return nil, nil
+			}
+		}
+		_ = ws.Close(CloseNormalClosure, "")
+		return nil, nil
+	})
+}
@@ -0,0 +1,202 @@
+package plaud
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"plaudern/utils"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writes a masked client frame
+func writeClientFrame(t *testing.T, conn net.Conn, fin bool, opcode byte, payload []byte) {
+	t.Helper()
+	first := opcode
+	if fin {
+		first |= 0x80
+	}
+	frame := []byte{first, 0x80 | byte(len(payload))}
+	mask := []byte{1, 2, 3, 4}
+	frame = append(frame, mask...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	if _, err := conn.Write(frame); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readServerFrame(t *testing.T, reader *bufio.Reader) (byte, []byte) {
+	t.Helper()
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		t.Fatal(err)
+	}
+	payload := make([]byte, header[1]&0x7f)
+	if _, err := io.ReadFull(reader, payload); err != nil {
+		t.Fatal(err)
+	}
+	return header[0] & 0x0f, payload
+}
+
+// headers are extra "Key: value" lines of the handshake
+func dialWebSocket(t *testing.T, addr, path, key string, headers ...string) (net.Conn, *bufio.Reader, *http.Response) {
+	t.Helper()
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handshake := "GET " + path + " HTTP/1.1\r\nHost: " + addr +
+		"\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Version: 13\r\n" +
+		"Sec-WebSocket-Key: " + key + "\r\n"
+	for _, header := range headers {
+		handshake += header + "\r\n"
+	}
+	handshake += "\r\n"
+	if _, err := conn.Write([]byte(handshake)); err != nil {
+		t.Fatal(err)
+	}
+	reader := bufio.NewReader(conn)
+	res, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn, reader, res
+}
+
+func TestWebSocketEcho(t *testing.T) {
+	server := New(":8000")
+	testRouter := NewRouter("/")
+	testRouter.WebSocket("/ws", func(_ *Context, ws *WebSocketConn) error {
+		for {
+			messageType, message, err := ws.ReadMessage()
+			if err != nil {
+				return err
+			}
+			if err := ws.WriteMessage(messageType, message); err != nil {
+				return err
+			}
+		}
+	})
+	server.Register(testRouter)
+
+	ts := httptest.NewServer(server.server)
+	defer ts.Close()
+	addr := strings.TrimPrefix(ts.URL, "http://")
+
+	conn, reader, res := dialWebSocket(t, addr, "/ws", "dGhlIHNhbXBsZSBub25jZQ==")
+	defer conn.Close()
+
+	utils.AssertEq(t, http.StatusSwitchingProtocols, res.StatusCode)
+	utils.AssertEq(t, "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=", res.Header.Get("Sec-WebSocket-Accept"))
+
+	// fragmented text message with a ping in between
+	writeClientFrame(t, conn, false, TextMessage, []byte("hel"))
+	writeClientFrame(t, conn, true, PingMessage, []byte("p"))
+	writeClientFrame(t, conn, true, continuationFrame, []byte("lo"))
+
+	opcode, payload := readServerFrame(t, reader)
+	utils.AssertEq(t, byte(PongMessage), opcode)
+	utils.AssertEq(t, "p", string(payload))
+
+	opcode, payload = readServerFrame(t, reader)
+	utils.AssertEq(t, byte(TextMessage), opcode)
+	utils.AssertEq(t, "hello", string(payload))
+
+	closePayload := binary.BigEndian.AppendUint16(nil, CloseNormalClosure)
+	writeClientFrame(t, conn, true, CloseMessage, closePayload)
+	opcode, payload = readServerFrame(t, reader)
+	utils.AssertEq(t, byte(CloseMessage), opcode)
+	utils.AssertEq(t, uint16(CloseNormalClosure), binary.BigEndian.Uint16(payload))
+}
+
+func TestWebSocketMiddleware(t *testing.T) {
+	server := New(":8000")
+	testRouter := NewRouter("/").Use(func(ctx *Context) *Error {
+		return ctx.AbortWithError("Unauthorized", http.StatusUnauthorized)
+	})
+	testRouter.WebSocket("/ws", func(_ *Context, _ *WebSocketConn) error {
+		t.Fatal("Upgrade should not happen when a middleware aborts")
+		return nil
+	})
+	server.Register(testRouter)
+
+	ts := httptest.NewServer(server.server)
+	defer ts.Close()
+
+	conn, _, res := dialWebSocket(t, strings.TrimPrefix(ts.URL, "http://"), "/ws", "dGhlIHNhbXBsZSBub25jZQ==")
+	defer conn.Close()
+	utils.AssertEq(t, http.StatusUnauthorized, res.StatusCode)
+}
+
+func TestWebSocketOrigin(t *testing.T) {
+	server := New(":8000")
+	testRouter := NewRouter("/")
+	accept := func(_ *Context, _ *WebSocketConn) error { return nil }
+	testRouter.WebSocket("/ws", accept)
+	testRouter.WebSocket("/partner", accept, WithOrigins("https://partner.example"))
+	server.Register(testRouter)
+
+	ts := httptest.NewServer(server.server)
+	defer ts.Close()
+	addr := strings.TrimPrefix(ts.URL, "http://")
+
+	tests := []struct {
+		path   string
+		origin string
+		code   int
+	}{
+		{"/ws", "", http.StatusSwitchingProtocols},
+		{"/ws", "http://" + addr, http.StatusSwitchingProtocols},
+		{"/ws", "https://evil.example", http.StatusForbidden},
+		{"/partner", "https://partner.example", http.StatusSwitchingProtocols},
+		{"/partner", "https://evil.example", http.StatusForbidden},
+	}
+	for _, test := range tests {
+		var headers []string
+		if test.origin != "" {
+			headers = append(headers, "Origin: "+test.origin)
+		}
+		conn, _, res := dialWebSocket(t, addr, test.path, "dGhlIHNhbXBsZSBub25jZQ==", headers...)
+		conn.Close()
+		if res.StatusCode != test.code {
+			t.Fatalf("%s %s Expected: %v Got: %v", test.path, test.origin, test.code, res.StatusCode)
+		}
+	}
+}
+
+func TestWebSocketWriteTimeout(t *testing.T) {
+	returned := make(chan error, 1)
+	server := New(":8000")
+	testRouter := NewRouter("/")
+	testRouter.WebSocket("/ws", func(_ *Context, ws *WebSocketConn) error {
+		ws.SetWriteTimeout(50 * time.Millisecond)
+		message := make([]byte, 64<<10)
+		for {
+			if err := ws.WriteMessage(BinaryMessage, message); err != nil {
+				returned <- err
+				return err
+			}
+		}
+	})
+	server.Register(testRouter)
+
+	ts := httptest.NewServer(server.server)
+	defer ts.Close()
+
+	// the client never reads
+	conn, _, _ := dialWebSocket(t, strings.TrimPrefix(ts.URL, "http://"), "/ws", "dGhlIHNhbXBsZSBub25jZQ==")
+	defer conn.Close()
+
+	select {
+	case err := <-returned:
+		utils.AssertNoEq(t, nil, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Write blocked on a client that stopped reading")
+	}
+}